@@ -3,8 +3,8 @@ module github.com/harness-community/drone-findfiles
 go 1.12
 
 require (
-	github.com/georgeJobs/go-antpathmatcher v0.0.0-20231023102852-19d9ea929586
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/text v0.16.0
 )
@@ -0,0 +1,451 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// antPattern is a compiled Ant style pattern. Patterns are split
+// into path segments, where a ** segment matches zero or more
+// directories and the other segments are matched against a single
+// path segment.
+//
+// As with the go-antpathmatcher package the plugin used to match Ant
+// patterns with, the last ** of a pattern holding several of them
+// matches at least one directory, so that **/harness/** matches the
+// contents of the harness directories but not the directories
+// themselves.
+type antPattern struct {
+	absolute bool
+	segments []antSegment
+}
+
+// antSegment is a single segment of an Ant style pattern.
+type antSegment struct {
+	doubleStar bool
+	// nonEmpty reports whether a ** segment matches at least one
+	// directory.
+	nonEmpty bool
+	tokens   []token
+}
+
+// tokenKind identifies the kind of a segment token.
+type tokenKind int
+
+const (
+	// tokenLiteral matches a single rune.
+	tokenLiteral tokenKind = iota
+	// tokenAnyRune matches any single rune, written ?.
+	tokenAnyRune
+	// tokenAnyString matches zero or more runes, written *.
+	tokenAnyString
+	// tokenClass matches a rune in a character class, written [...].
+	tokenClass
+	// tokenGroup matches a pattern group such as @(a|b) or !(a).
+	tokenGroup
+)
+
+// token is a parsed element of a pattern segment.
+type token struct {
+	kind  tokenKind
+	r     rune
+	class charClass
+	group patternGroup
+}
+
+// patternGroup is an extglob style group of alternatives. The
+// operator selects how many times the alternatives match: ? zero or
+// one time, * zero or more times, + one or more times, @ exactly one
+// time, and ! matches anything the alternatives do not match.
+type patternGroup struct {
+	op           byte
+	alternatives [][]token
+}
+
+// charClass is a bracket expression such as [a-z] or [!0-9].
+type charClass struct {
+	negated bool
+	ranges  []runeRange
+}
+
+// runeRange is an inclusive range of runes in a character class.
+type runeRange struct {
+	lo, hi rune
+}
+
+// parseAntPattern parses an Ant style pattern. Brace alternatives
+// must already be expanded.
+func parseAntPattern(pattern string) (antPattern, error) {
+	p := antPattern{absolute: strings.HasPrefix(pattern, "/")}
+	for _, text := range strings.Split(pattern, "/") {
+		if text == "" {
+			continue
+		}
+		if text == "**" {
+			// consecutive ** segments match like a single one.
+			if n := len(p.segments); n == 0 || !p.segments[n-1].doubleStar {
+				p.segments = append(p.segments, antSegment{doubleStar: true})
+			}
+			continue
+		}
+		tokens, err := parseSegment(text)
+		if err != nil {
+			return antPattern{}, err
+		}
+		p.segments = append(p.segments, antSegment{tokens: tokens})
+	}
+
+	last, count := -1, 0
+	for i, segment := range p.segments {
+		if segment.doubleStar {
+			last, count = i, count+1
+		}
+	}
+	if count > 1 {
+		p.segments[last].nonEmpty = true
+	}
+	return p, nil
+}
+
+// match reports whether the path matches the pattern. An absolute
+// pattern only matches absolute paths, and a relative pattern only
+// matches relative paths.
+func (p antPattern) match(path string) bool {
+	if strings.HasPrefix(path, "/") != p.absolute {
+		return false
+	}
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return matchSegments(p.segments, names)
+}
+
+// matchSegments matches pattern segments against path segments.
+func matchSegments(segments []antSegment, names []string) bool {
+	for len(segments) > 0 {
+		if segments[0].doubleStar {
+			skip := 0
+			if segments[0].nonEmpty {
+				skip = 1
+			}
+			segments = segments[1:]
+			if len(segments) == 0 {
+				return len(names) >= skip
+			}
+			for i := skip; i < len(names); i++ {
+				if matchSegments(segments, names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 || !matchTokens(segments[0].tokens, names[0]) {
+			return false
+		}
+		segments, names = segments[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// parseSegment parses a pattern segment into tokens. A backslash
+// escapes the following rune.
+func parseSegment(text string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		if strings.ContainsRune("?*+@!", r) && i+1 < len(text) && text[i+1] == '(' {
+			group, size, err := parseGroup(text[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenGroup, group: group})
+			i += size
+			continue
+		}
+		switch r {
+		case '*':
+			// consecutive stars inside a segment behave as one.
+			if len(tokens) == 0 || tokens[len(tokens)-1].kind != tokenAnyString {
+				tokens = append(tokens, token{kind: tokenAnyString})
+			}
+		case '?':
+			tokens = append(tokens, token{kind: tokenAnyRune})
+		case '[':
+			class, size, err := parseClass(text[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenClass, class: class})
+			n = size
+		case '\\':
+			if i+n == len(text) {
+				return nil, errors.New("trailing backslash")
+			}
+			r, size := utf8.DecodeRuneInString(text[i+n:])
+			tokens = append(tokens, token{kind: tokenLiteral, r: r})
+			n += size
+		default:
+			tokens = append(tokens, token{kind: tokenLiteral, r: r})
+		}
+		i += n
+	}
+	return tokens, nil
+}
+
+// parseGroup parses the pattern group at the start of text, such as
+// !(*.local.*), returning the group and the number of bytes consumed.
+// Alternatives are separated by | and may contain nested groups.
+func parseGroup(text string) (patternGroup, int, error) {
+	group := patternGroup{op: text[0]}
+	depth, last := 0, 2
+	for i := 2; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '[':
+			// bracket expressions may hold ( ) and | runes.
+			if _, size, err := parseClass(text[i:]); err == nil {
+				i += size - 1
+			}
+		case '(':
+			depth++
+		case '|', ')':
+			if depth > 0 {
+				if text[i] == ')' {
+					depth--
+				}
+				continue
+			}
+			tokens, err := parseSegment(text[last:i])
+			if err != nil {
+				return patternGroup{}, 0, err
+			}
+			group.alternatives = append(group.alternatives, tokens)
+			last = i + 1
+			if text[i] == ')' {
+				return group, i + 1, nil
+			}
+		}
+	}
+	return patternGroup{}, 0, errors.New("unterminated pattern group")
+}
+
+// parseClass parses the bracket expression at the start of text,
+// returning the class and the number of bytes consumed. A leading
+// ! or ^ negates the class and a ] is literal when it comes first.
+func parseClass(text string) (charClass, int, error) {
+	var class charClass
+	i := 1
+	if i < len(text) && (text[i] == '!' || text[i] == '^') {
+		class.negated = true
+		i++
+	}
+	for first := true; ; first = false {
+		if i >= len(text) {
+			return charClass{}, 0, errors.New("unterminated character class")
+		}
+		if text[i] == ']' && !first {
+			break
+		}
+		lo, n, err := classRune(text[i:])
+		if err != nil {
+			return charClass{}, 0, err
+		}
+		i += n
+		hi := lo
+		if i+1 < len(text) && text[i] == '-' && text[i+1] != ']' {
+			hi, n, err = classRune(text[i+1:])
+			if err != nil {
+				return charClass{}, 0, err
+			}
+			if hi < lo {
+				return charClass{}, 0, errors.New("invalid character class range")
+			}
+			i += n + 1
+		}
+		class.ranges = append(class.ranges, runeRange{lo: lo, hi: hi})
+	}
+	return class, i + 1, nil
+}
+
+// classRune decodes a possibly escaped rune of a bracket expression.
+func classRune(text string) (rune, int, error) {
+	r, n := utf8.DecodeRuneInString(text)
+	if r != '\\' {
+		return r, n, nil
+	}
+	if n == len(text) {
+		return 0, 0, errors.New("unterminated character class")
+	}
+	r, size := utf8.DecodeRuneInString(text[n:])
+	return r, n + size, nil
+}
+
+// matches reports whether the rune belongs to the class.
+func (c charClass) matches(r rune) bool {
+	for _, rr := range c.ranges {
+		if rr.lo <= r && r <= rr.hi {
+			return !c.negated
+		}
+	}
+	return c.negated
+}
+
+// matches reports whether the group matches the whole name.
+func (g patternGroup) matches(name string) bool {
+	switch g.op {
+	case '?':
+		return name == "" || g.matchesOne(name)
+	case '*':
+		return name == "" || g.matchesMany(name)
+	case '+':
+		return g.matchesMany(name)
+	case '@':
+		return g.matchesOne(name)
+	default:
+		return !g.matchesOne(name)
+	}
+}
+
+// matchesOne reports whether one of the alternatives matches the
+// whole name.
+func (g patternGroup) matchesOne(name string) bool {
+	for _, alternative := range g.alternatives {
+		if matchTokens(alternative, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesMany reports whether the name is a sequence of one or more
+// strings each matching one of the alternatives.
+func (g patternGroup) matchesMany(name string) bool {
+	if g.matchesOne(name) {
+		return true
+	}
+	for i := 1; i < len(name); i++ {
+		if utf8.RuneStart(name[i]) && g.matchesOne(name[:i]) && g.matchesMany(name[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTokens reports whether the tokens match the whole name.
+func matchTokens(tokens []token, name string) bool {
+	for len(tokens) > 0 {
+		t := tokens[0]
+		if t.kind == tokenGroup {
+			for i := 0; i <= len(name); i++ {
+				if i < len(name) && !utf8.RuneStart(name[i]) {
+					continue
+				}
+				if t.group.matches(name[:i]) && matchTokens(tokens[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if t.kind == tokenAnyString {
+			if len(tokens) == 1 {
+				return true
+			}
+			for i := range name {
+				if matchTokens(tokens[1:], name[i:]) {
+					return true
+				}
+			}
+			return matchTokens(tokens[1:], "")
+		}
+		if name == "" {
+			return false
+		}
+		r, n := utf8.DecodeRuneInString(name)
+		switch t.kind {
+		case tokenLiteral:
+			if r != t.r {
+				return false
+			}
+		case tokenClass:
+			if !t.class.matches(r) {
+				return false
+			}
+		}
+		tokens, name = tokens[1:], name[n:]
+	}
+	return name == ""
+}
+
+// expandBraces expands brace alternatives such as {yml,yaml} into
+// the list of patterns they describe. Braces may be nested, and
+// braces without a comma are kept literally.
+func expandBraces(pattern string) []string {
+	start, end, alternatives := findBraces(pattern)
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	var patterns []string
+	seen := map[string]bool{}
+	for _, alternative := range alternatives {
+		for _, expanded := range expandBraces(pattern[:start] + alternative + pattern[end+1:]) {
+			if !seen[expanded] {
+				seen[expanded] = true
+				patterns = append(patterns, expanded)
+			}
+		}
+	}
+	return patterns
+}
+
+// findBraces locates the first brace group with at least one
+// top-level comma, returning the offsets of its braces and its
+// alternatives, or -1 when there is none.
+func findBraces(pattern string) (int, int, []string) {
+	for start := 0; start < len(pattern); start++ {
+		switch pattern[start] {
+		case '\\':
+			start++
+			continue
+		case '{':
+		default:
+			continue
+		}
+
+		depth, last := 0, start+1
+		var alternatives []string
+	scan:
+		for i := start + 1; i < len(pattern); i++ {
+			switch pattern[i] {
+			case '\\':
+				i++
+			case '{':
+				depth++
+			case ',':
+				if depth == 0 {
+					alternatives = append(alternatives, pattern[last:i])
+					last = i + 1
+				}
+			case '}':
+				if depth > 0 {
+					depth--
+					continue
+				}
+				if len(alternatives) == 0 {
+					break scan
+				}
+				return start, i, append(alternatives, pattern[last:i])
+			}
+		}
+	}
+	return -1, -1, nil
+}
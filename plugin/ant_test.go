@@ -0,0 +1,239 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_antPattern_Wildcards(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{pattern: "**/*.txt", path: "one.txt", matched: true},
+		{pattern: "**/*.txt", path: "abc/def/one.txt", matched: true},
+		{pattern: "**/*.txt", path: "/abc/one.txt", matched: false},
+		{pattern: "/**/*.txt", path: "/abc/one.txt", matched: true},
+		{pattern: "abc/*", path: "abc/def/one.txt", matched: false},
+		{pattern: "abc/**", path: "abc", matched: true},
+		{pattern: "**/def/**", path: "abc/def/one.txt", matched: true},
+		{pattern: "**/**/def/**", path: "abc/def/one.txt", matched: true},
+		{pattern: "**/a/**/b", path: "a/x/b", matched: true},
+		{pattern: "**/a/**/b/**", path: "a/b/c", matched: true},
+		{pattern: "?.xyz", path: "a.xyz", matched: true},
+		{pattern: "?.xyz", path: "a1.xyz", matched: false},
+		{pattern: `abc/\*.txt`, path: "abc/*.txt", matched: true},
+		{pattern: `abc/\*.txt`, path: "abc/one.txt", matched: false},
+	}
+	for _, test := range tests {
+		m, err := compileAnt(test.pattern, MatchOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, test.matched, m.Match(test.path), "%q against %q", test.pattern, test.path)
+	}
+}
+
+// The last ** of a pattern holding several of them matches at least
+// one directory, as it did with the go-antpathmatcher package.
+func Test_antPattern_LastDoubleStar(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{pattern: "**/def/**", path: "abc/def", matched: false},
+		{pattern: "**/**/def/**", path: "abc/def", matched: false},
+		{pattern: "**/a/**/b", path: "a/b", matched: false},
+		{pattern: "**/a/**/b/**", path: "a/b", matched: false},
+		{pattern: "abc/**", path: "abc", matched: true},
+		{pattern: "abc/**/def/**", path: "abc/def", matched: false},
+		{pattern: "abc/**/def/**", path: "abc/def/one.txt", matched: true},
+	}
+	for _, test := range tests {
+		m, err := compileAnt(test.pattern, MatchOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, test.matched, m.Match(test.path), "%q against %q", test.pattern, test.path)
+	}
+}
+
+func Test_antPattern_Braces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{pattern: "**/*.{yml,yaml}", path: "charts/values.yml", matched: true},
+		{pattern: "**/*.{yml,yaml}", path: "charts/values.yaml", matched: true},
+		{pattern: "**/*.{yml,yaml}", path: "charts/values.json", matched: false},
+		{pattern: "{src,lib}/**/*.go", path: "lib/x/y.go", matched: true},
+		{pattern: "{src,lib}/**/*.go", path: "cmd/y.go", matched: false},
+		{pattern: "*.{a,{b,c}}", path: "x.c", matched: true},
+		{pattern: "*.{a,}", path: "x.", matched: true},
+		{pattern: "{single}.txt", path: "{single}.txt", matched: true},
+		{pattern: `\{a,b\}.txt`, path: "{a,b}.txt", matched: true},
+		{pattern: `\{a,b\}.txt`, path: "a.txt", matched: false},
+	}
+	for _, test := range tests {
+		m, err := compileAnt(test.pattern, MatchOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, test.matched, m.Match(test.path), "%q against %q", test.pattern, test.path)
+	}
+}
+
+func Test_antPattern_CharacterClasses(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{pattern: "**/build-[0-9]*/**", path: "out/build-42/app.jar", matched: true},
+		{pattern: "**/build-[0-9]*/**", path: "out/build-x/app.jar", matched: false},
+		{pattern: "file[abc].txt", path: "fileb.txt", matched: true},
+		{pattern: "file[abc].txt", path: "filed.txt", matched: false},
+		{pattern: "file[!0-9].txt", path: "filex.txt", matched: true},
+		{pattern: "file[!0-9].txt", path: "file7.txt", matched: false},
+		{pattern: "file[^0-9].txt", path: "file7.txt", matched: false},
+		{pattern: "file[]x].txt", path: "file].txt", matched: true},
+		{pattern: "file[a-].txt", path: "file-.txt", matched: true},
+		{pattern: `file[\]].txt`, path: "file].txt", matched: true},
+		{pattern: "r[é]sumé.pdf", path: "résumé.pdf", matched: true},
+	}
+	for _, test := range tests {
+		m, err := compileAnt(test.pattern, MatchOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, test.matched, m.Match(test.path), "%q against %q", test.pattern, test.path)
+	}
+}
+
+func Test_antPattern_Groups(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{pattern: "config/!(*.local.*)", path: "config/app.yml", matched: true},
+		{pattern: "config/!(*.local.*)", path: "config/app.local.yml", matched: false},
+		{pattern: "config/**/!(*.local.*)", path: "config/dev/db.yml", matched: true},
+		{pattern: "config/**/!(*.local.*)", path: "config/dev/db.local.yml", matched: false},
+		{pattern: "**/*.@(yml|yaml)", path: "charts/values.yaml", matched: true},
+		{pattern: "**/*.@(yml|yaml)", path: "charts/values.yamlx", matched: false},
+		{pattern: "file+([0-9]).txt", path: "file123.txt", matched: true},
+		{pattern: "file+([0-9]).txt", path: "file.txt", matched: false},
+		{pattern: "file*([0-9]).txt", path: "file.txt", matched: true},
+		{pattern: "file*([0-9]).txt", path: "file1a.txt", matched: false},
+		{pattern: "file?(-v2).txt", path: "file-v2.txt", matched: true},
+		{pattern: "file?(-v2).txt", path: "file-v2-v2.txt", matched: false},
+		{pattern: "+(ab|c).txt", path: "abcab.txt", matched: true},
+		{pattern: "@(a|!(b*)).txt", path: "bc.txt", matched: false},
+		{pattern: "@(a|!(b*)).txt", path: "cd.txt", matched: true},
+		{pattern: "!(@(x|y)).go", path: "x.go", matched: false},
+		{pattern: "@([|]|x).txt", path: "|.txt", matched: true},
+		{pattern: `\!(a).txt`, path: "!(a).txt", matched: true},
+		{pattern: "(a|b).txt", path: "(a|b).txt", matched: true},
+		{pattern: "**/!(vendor)/*.go", path: "vendor/x.go", matched: false},
+		{pattern: "**/!(vendor)/*.go", path: "abc/vendor/x.go", matched: false},
+		{pattern: "**/!(vendor)/*.go", path: "abc/x.go", matched: true},
+	}
+	for _, test := range tests {
+		m, err := compileAnt(test.pattern, MatchOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, test.matched, m.Match(test.path), "%q against %q", test.pattern, test.path)
+	}
+}
+
+func Test_antPattern_Invalid(t *testing.T) {
+	tests := []struct {
+		pattern string
+		err     string
+	}{
+		{pattern: "file[abc.txt", err: "unterminated character class"},
+		{pattern: "file[z-a].txt", err: "invalid character class range"},
+		{pattern: `file\`, err: "trailing backslash"},
+		{pattern: "@(a|b", err: "unterminated pattern group"},
+		{pattern: "!(a/b)", err: "unterminated pattern group"},
+		{pattern: "@(a|[b)", err: "unterminated character class"},
+	}
+	for _, test := range tests {
+		_, err := compileAnt(test.pattern, MatchOptions{})
+		assert.EqualError(t, err, test.err, test.pattern)
+	}
+}
+
+func Test_expandBraces(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expanded []string
+	}{
+		{pattern: "*.txt", expanded: []string{"*.txt"}},
+		{pattern: "*.{yml,yaml}", expanded: []string{"*.yml", "*.yaml"}},
+		{pattern: "{a,b}/{c,d}", expanded: []string{"a/c", "a/d", "b/c", "b/d"}},
+		{pattern: "{a,{b,c}}", expanded: []string{"a", "b", "c"}},
+		{pattern: "{a,a}", expanded: []string{"a"}},
+		{pattern: "{a}{b,c}", expanded: []string{"{a}b", "{a}c"}},
+		{pattern: "{a,b", expanded: []string{"{a,b"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expanded, expandBraces(test.pattern), test.pattern)
+	}
+}
+
+func Test_splitPatterns_Braces(t *testing.T) {
+	assert.Equal(t, []string{"**/*.{yml,yaml}", "**/*.json"}, splitPatterns("**/*.{yml,yaml},**/*.json"))
+}
+
+func Test_Exec_Relative_BracesAndClasses(t *testing.T) {
+	setupRelativeFilesAndFolders()
+	defer cleanupRelativeFilesAndFolders()
+
+	fatalIf(os.MkdirAll("abc/build-1", 0755))
+	fatalIf(os.WriteFile("abc/build-1/app.yaml", []byte{}, 0644))
+	fatalIf(os.MkdirAll("abc/build-x", 0755))
+	fatalIf(os.WriteFile("abc/build-x/app.yaml", []byte{}, 0644))
+
+	args := Args{
+		Filter:   "abc/**/*.{yml,yaml}",
+		Excludes: "**/build-[!0-9]*/**",
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, "abc/one.yml")
+	assert.Contains(t, paths, "abc/def/one.yml")
+	assert.Contains(t, paths, "abc/build-1/app.yaml")
+}
+
+func Test_Exec_Relative_NegatedGroup(t *testing.T) {
+	setupRelativeFilesAndFolders()
+	defer cleanupRelativeFilesAndFolders()
+
+	fatalIf(os.WriteFile("abc/def/one.local.yml", []byte{}, 0644))
+
+	args := Args{
+		Filter: "abc/def/!(*.local.*)",
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, "abc/def/one.txt")
+	assert.Contains(t, paths, "abc/def/one.yml")
+	assert.Contains(t, paths, "abc/def/one.xml")
+	assert.Contains(t, paths, "abc/def/two.txt")
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+// antDefaultExcludes is the set of patterns excluded by default by
+// Ant's DirectoryScanner, covering the metadata of version control
+// systems and editor backup files.
+var antDefaultExcludes = []string{
+	// Miscellaneous typical temporary files
+	"**/*~",
+	"**/#*#",
+	"**/.#*",
+	"**/%*%",
+	"**/._*",
+
+	// CVS
+	"**/CVS",
+	"**/CVS/**",
+	"**/.cvsignore",
+
+	// SCCS
+	"**/SCCS",
+	"**/SCCS/**",
+
+	// Visual SourceSafe
+	"**/vssver.scc",
+
+	// Subversion
+	"**/.svn",
+	"**/.svn/**",
+
+	// Git
+	"**/.git",
+	"**/.git/**",
+	"**/.gitattributes",
+	"**/.gitignore",
+	"**/.gitmodules",
+
+	// Mercurial
+	"**/.hg",
+	"**/.hg/**",
+	"**/.hgignore",
+	"**/.hgsub",
+	"**/.hgsubstate",
+	"**/.hgtags",
+
+	// Bazaar
+	"**/.bzr",
+	"**/.bzr/**",
+	"**/.bzrignore",
+
+	// Mac
+	"**/.DS_Store",
+}
+
+// defaultExcludes returns the compiled default excludes, extended
+// with the additional entries configured in the arguments. Default
+// excludes apply unless explicitly disabled, and are always Ant
+// patterns, relative to the search root, whatever the configured
+// match mode. They do not apply to Docker build contexts, which only
+// honor the .dockerignore file.
+func defaultExcludes(args Args) ([]pattern, error) {
+	if (args.DefaultExcludes != nil && !*args.DefaultExcludes) || args.DockerContext {
+		return nil, nil
+	}
+	patterns := append(append([]string{}, antDefaultExcludes...), splitPatterns(args.DefaultExcludesAdd)...)
+	return compilePatterns("ant", patterns, MatchOptions{})
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDefaultExcludes(tempDir string) {
+	fatalIf(os.MkdirAll(filepath.Join(tempDir, ".git/refs"), 0755))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, ".git/HEAD"), []byte{}, 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, ".git/refs/main"), []byte{}, 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/one.txt~"), []byte{}, 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/def/.DS_Store"), []byte{}, 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/one.bak"), []byte{}, 0644))
+}
+
+// boolPtr returns a pointer to b, for optional boolean arguments.
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func Test_Exec_Absolute_DefaultExcludes(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+	setupDefaultExcludes(tempDir)
+
+	args := Args{
+		Filter:    "**/abc/**,**/.git/**,**/.git",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.bak"))
+	assert.NotContains(t, paths, filepath.Join(tempDir, "abc/one.txt~"))
+	assert.NotContains(t, paths, filepath.Join(tempDir, "abc/def/.DS_Store"))
+	assert.NotContains(t, paths, filepath.Join(tempDir, ".git"))
+	assert.NotContains(t, paths, filepath.Join(tempDir, ".git/HEAD"))
+	assert.NotContains(t, paths, filepath.Join(tempDir, ".git/refs/main"))
+}
+
+func Test_Exec_Absolute_DefaultExcludesAdd(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+	setupDefaultExcludes(tempDir)
+
+	args := Args{
+		Filter:             "**/abc/*",
+		DefaultExcludesAdd: "**/*.bak",
+		TargetDir:          tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.NotContains(t, paths, filepath.Join(tempDir, "abc/one.bak"))
+	assert.NotContains(t, paths, filepath.Join(tempDir, "abc/one.txt~"))
+}
+
+func Test_Exec_Absolute_DefaultExcludesDisabled(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+	setupDefaultExcludes(tempDir)
+
+	args := Args{
+		Filter:             "**/.git/*,**/*~",
+		DefaultExcludes:    boolPtr(false),
+		DefaultExcludesAdd: "**/*~",
+		TargetDir:          tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, ".git/HEAD"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt~"))
+}
+
+func Test_Exec_DefaultExcludesSkipDirectories(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+	setupDefaultExcludes(tempDir)
+
+	explainFile := filepath.Join(tempDir, "explain.txt")
+	args := Args{
+		Filter:      "**",
+		TargetDir:   tempDir,
+		Explain:     true,
+		ExplainFile: explainFile,
+	}
+
+	_, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+	assert.Contains(t, string(content), "verdict: excluded, default exclude **/.git matched, directory not descended into\n")
+	assert.NotContains(t, string(content), filepath.Join(tempDir, ".git/HEAD"))
+	assert.NotContains(t, string(content), filepath.Join(tempDir, ".git/refs"))
+}
@@ -0,0 +1,17 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+//go:build windows || plan9
+
+package plugin
+
+import (
+	"os"
+)
+
+// deviceID returns the ID of the device holding a file, which is not
+// known on this platform.
+func deviceID(fi os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+//go:build !windows && !plan9
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_deviceID(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	root, err := os.Stat(tempDir)
+	fatalIf(err)
+	file, err := os.Stat(filepath.Join(tempDir, "abc/def/one.txt"))
+	fatalIf(err)
+
+	rootID, ok := deviceID(root)
+	assert.True(t, ok)
+	fileID, ok := deviceID(file)
+	assert.True(t, ok)
+	assert.Equal(t, rootID, fileID)
+}
+
+func Test_search_MountPoint(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	explainFile := filepath.Join(tempDir, "explain.txt")
+	s, err := newSearch(NoopLogger(), Args{
+		Filter:        "**",
+		TargetDir:     tempDir,
+		OneFileSystem: true,
+		Explain:       true,
+		ExplainFile:   explainFile,
+	})
+	fatalIf(err)
+	if !assert.NotNil(t, s.device) {
+		return
+	}
+
+	entry := dirEntry(tempDir, "abc")
+	assert.False(t, s.mountPoint(entry))
+
+	// pretend the search directory is held by another device.
+	*s.device++
+	assert.True(t, s.mountPoint(entry))
+
+	dec, err := s.decide(filepath.Join(tempDir, "abc"), entry)
+	fatalIf(s.close())
+	assert.NoError(t, err)
+	assert.True(t, dec.include)
+	assert.True(t, dec.skipDir)
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+	assert.Contains(t, string(content), "verdict: included, include pattern ** matched, on another file system, directory not descended into")
+}
+
+// dirEntry returns the directory entry of a file listed in dir.
+func dirEntry(dir, name string) os.DirEntry {
+	entries, err := os.ReadDir(dir)
+	fatalIf(err)
+	for _, entry := range entries {
+		if entry.Name() == name {
+			return entry
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// dockerignore applies the rules of a .dockerignore file the way
+// the Docker CLI does when it builds the context sent to the daemon.
+type dockerignore struct {
+	// file is the ignore file the rules were read from, empty when
+	// the build context has no ignore file.
+	file  string
+	rules []dockerRule
+
+	// exceptions reports whether any rule starts with !, in which
+	// case excluded directories must still be walked.
+	exceptions bool
+}
+
+// dockerRule is a single parsed .dockerignore pattern, with the
+// file and line it was read from. Rules added for the Dockerfile and
+// the ignore file itself have no line.
+type dockerRule struct {
+	text      string
+	file      string
+	line      int
+	exception bool
+	re        *regexp.Regexp
+}
+
+// newDockerignore reads the ignore file of the build context rooted
+// at dir. A <Dockerfile>.dockerignore file next to the Dockerfile
+// takes precedence over the .dockerignore file of the context.
+func newDockerignore(dir, dockerfile string) (*dockerignore, error) {
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	d := &dockerignore{}
+	for _, name := range []string{
+		filepath.Join(dir, filepath.FromSlash(dockerfile)+".dockerignore"),
+		filepath.Join(dir, ".dockerignore"),
+	} {
+		content, err := os.ReadFile(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ignore file: %w", err)
+		}
+		d.file = name
+		if err := d.parse(content); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		break
+	}
+
+	// like the Docker CLI, the Dockerfile and the .dockerignore file
+	// are always sent to the daemon even when they are excluded.
+	if excluded, _ := d.excluded(".dockerignore"); excluded {
+		d.add("!.dockerignore", 0)
+	}
+	rel := path.Clean(filepath.ToSlash(dockerfile))
+	if excluded, _ := d.excluded(rel); excluded {
+		d.add("!"+rel, 0)
+	}
+	return d, nil
+}
+
+// parse reads the rules of an ignore file.
+func (d *dockerignore) parse(content []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		// comments are only recognized before trimming.
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if err := d.add(text, line); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// add parses and appends a rule read from the given line of the
+// ignore file, or zero for a rule the file does not hold.
+func (d *dockerignore) add(text string, line int) error {
+	rule := dockerRule{text: text, line: line}
+	if line > 0 {
+		rule.file = d.file
+	}
+	if strings.HasPrefix(text, "!") {
+		rule.exception = true
+		text = strings.TrimSpace(text[1:])
+	}
+	if text != "" {
+		text = path.Clean(filepath.ToSlash(text))
+		if len(text) > 1 && text[0] == '/' {
+			text = text[1:]
+		}
+	}
+
+	re, err := regexp.Compile(translateDockerPattern(text))
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", rule.text, err)
+	}
+	rule.re = re
+
+	d.rules = append(d.rules, rule)
+	d.exceptions = d.exceptions || rule.exception
+	return nil
+}
+
+// excluded reports whether a path relative to the build context is
+// excluded, along with the rule that decided it, or nil when no rule
+// matched. Rules are evaluated in order and the last matching rule
+// wins, where a rule also matches a path when it matches one of its
+// parent directories.
+func (d *dockerignore) excluded(rel string) (bool, *dockerRule) {
+	var parents []string
+	if dir := path.Dir(rel); dir != "." {
+		parents = strings.Split(dir, "/")
+	}
+
+	excluded := false
+	var decided *dockerRule
+	for i := range d.rules {
+		rule := &d.rules[i]
+		// a rule can only change the verdict when it is an exception
+		// for an excluded path or an exclusion for an included path.
+		if rule.exception != excluded {
+			continue
+		}
+		matched := rule.re.MatchString(rel)
+		for i := range parents {
+			if matched {
+				break
+			}
+			matched = rule.re.MatchString(strings.Join(parents[:i+1], "/"))
+		}
+		if matched {
+			excluded = !rule.exception
+			decided = rule
+		}
+	}
+	return excluded, decided
+}
+
+// translateDockerPattern translates a .dockerignore pattern into a
+// regular expression. Unlike Ant and gitignore patterns, ** matches
+// any number of characters including separators wherever it appears.
+func translateDockerPattern(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				// treat **/ as **.
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+				}
+				if i+1 == len(pattern) {
+					b.WriteString(".*")
+				} else {
+					b.WriteString("(.*/)?")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			b.WriteString("[")
+			if i+1 < len(pattern) && pattern[i+1] == '!' {
+				b.WriteString("^")
+				i++
+			}
+		case ']':
+			b.WriteString("]")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			} else {
+				b.WriteString(`\\`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
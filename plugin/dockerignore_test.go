@@ -0,0 +1,134 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dockerignore_Excluded(t *testing.T) {
+	tests := []struct {
+		rules    string
+		path     string
+		excluded bool
+	}{
+		{rules: "*.go", path: "main.go", excluded: true},
+		{rules: "*.go", path: "cmd/main.go", excluded: false},
+		{rules: "**/*.go", path: "cmd/app/main.go", excluded: true},
+		{rules: "**", path: "cmd/app/main.go", excluded: true},
+		{rules: "docs", path: "docs/guide/index.md", excluded: true},
+		{rules: "/docs", path: "docs/index.md", excluded: true},
+		{rules: "build/../docs", path: "docs/index.md", excluded: true},
+		{rules: "docs/**/*.png", path: "docs/a/b/c.png", excluded: true},
+		{rules: "*.md\n!README.md", path: "README.md", excluded: false},
+		{rules: "*.md\n!README.md", path: "CHANGELOG.md", excluded: true},
+		{rules: "!README.md\n*.md", path: "README.md", excluded: true},
+		{rules: "docs\n!docs/keep.md", path: "docs/keep.md", excluded: false},
+		{rules: "file[0-9].txt", path: "file7.txt", excluded: true},
+		{rules: "file[!0-9].txt", path: "file7.txt", excluded: false},
+		{rules: "# *.go\n  # comment", path: "main.go", excluded: false},
+	}
+	for _, test := range tests {
+		d := &dockerignore{}
+		assert.NoError(t, d.parse([]byte(test.rules)))
+		excluded, _ := d.excluded(test.path)
+		assert.Equal(t, test.excluded, excluded, "%q against %q", test.rules, test.path)
+	}
+}
+
+func setupDockerContext() string {
+	tempDir := setupFilesAndFolders()
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, ".dockerignore"), []byte("*\n!abc\nabc/def\nabc/test/**/*.go\n"), 0644))
+	return tempDir
+}
+
+func Test_Exec_DockerContext(t *testing.T) {
+	tempDir := setupDockerContext()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/one.txt"), []byte("12345"), 0644))
+
+	args := Args{
+		DockerContext: true,
+		TargetDir:     tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		filepath.Join(tempDir, ".dockerignore"),
+		filepath.Join(tempDir, "Dockerfile"),
+		filepath.Join(tempDir, "abc/one.txt"),
+		filepath.Join(tempDir, "abc/one.yml"),
+		filepath.Join(tempDir, "abc/two.txt"),
+		filepath.Join(tempDir, "abc/test/harness/community/go.mod"),
+		filepath.Join(tempDir, "abc/test/harness/community/go.sum"),
+	}, paths)
+}
+
+func Test_Exec_DockerContext_DockerfileIgnore(t *testing.T) {
+	tempDir := setupDockerContext()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.MkdirAll(filepath.Join(tempDir, "build"), 0755))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "build/app.Dockerfile"), []byte("FROM scratch\n"), 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "build/app.Dockerfile.dockerignore"), []byte("**\n!**/*.xyz\n"), 0644))
+
+	args := Args{
+		Filter:        "a*",
+		DockerContext: true,
+		Dockerfile:    "build/app.Dockerfile",
+		TargetDir:     tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		filepath.Join(tempDir, "a.xyz"),
+		filepath.Join(tempDir, "a1.xyz"),
+	}, paths)
+}
+
+func Test_Exec_DockerContext_Size(t *testing.T) {
+	tempDir := setupDockerContext()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/one.txt"), []byte("12345"), 0644))
+
+	output := filepath.Join(tempDir, "drone_output.properties")
+	os.Setenv("DRONE_OUTPUT", output)
+	defer os.Unsetenv("DRONE_OUTPUT")
+
+	err := Exec(context.Background(), Args{
+		Filter:        "abc/*.txt",
+		DockerContext: true,
+		TargetDir:     tempDir,
+	})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(output)
+	fatalIf(err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "FILES_INFO="))
+	assert.Equal(t, "CONTEXT_SIZE=5", lines[1])
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultExplainFile is the file the trace is written to when none
+// is configured.
+const defaultExplainFile = "findfiles-explain.txt"
+
+// trace writes, for every visited path, the rules evaluated for it
+// and the final verdict. A nil trace records nothing.
+type trace struct {
+	name   string
+	abs    string
+	file   *os.File
+	w      *bufio.Writer
+	checks []string
+}
+
+// newTrace creates the trace file, replacing an existing file.
+func newTrace(logger *logrus.Entry, name string) (*trace, error) {
+	if name == "" {
+		name = defaultExplainFile
+	}
+	if _, err := os.Stat(name); err == nil {
+		logger.Warnf("explain file %s already exists and is overwritten", name)
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create explain file: %w", err)
+	}
+	return &trace{name: name, abs: absPath(name), file: file, w: bufio.NewWriter(file)}, nil
+}
+
+// traces reports whether a walked path is the trace file, which may be
+// written inside the search directory.
+func (t *trace) traces(path string) bool {
+	return t != nil && absPath(path) == t.abs
+}
+
+// check records the evaluation of a rule for the current path.
+func (t *trace) check(kind, pattern string, matched bool) {
+	if t == nil {
+		return
+	}
+	result := "no match"
+	if matched {
+		result = "match"
+	}
+	if pattern == "" {
+		t.checks = append(t.checks, fmt.Sprintf("%s: %s", kind, result))
+	} else {
+		t.checks = append(t.checks, fmt.Sprintf("%s %s: %s", kind, pattern, result))
+	}
+}
+
+// ruleSource describes an ignore file rule for the trace as the file
+// and line it was read from followed by the rule, or as the rule alone
+// when it was not read from a file.
+func ruleSource(file string, line int, text string) string {
+	if file == "" {
+		return text
+	}
+	return fmt.Sprintf("%s:%d %s", file, line, text)
+}
+
+// verdict writes the checks recorded for the path followed by the
+// decision.
+func (t *trace) verdict(path string, dec decision) {
+	if t == nil {
+		return
+	}
+	verdict := "excluded"
+	if dec.include {
+		verdict = "included"
+	}
+	fmt.Fprintln(t.w, path)
+	for _, check := range t.checks {
+		fmt.Fprintf(t.w, "  %s\n", check)
+	}
+	fmt.Fprintf(t.w, "  verdict: %s, %s\n", verdict, dec.reason)
+	t.checks = t.checks[:0]
+}
+
+// close flushes and closes the trace file.
+func (t *trace) close() error {
+	if err := t.w.Flush(); err != nil {
+		t.file.Close()
+		return fmt.Errorf("failed to write explain file: %w", err)
+	}
+	return t.file.Close()
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Exec_Explain(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	explainFile := filepath.Join(tempDir, "explain.txt")
+	args := Args{
+		Filter:          "*.txt,**/one.*",
+		Excludes:        "**/*.yml",
+		DefaultExcludes: boolPtr(false),
+		TargetDir:       filepath.Join(tempDir, "abc"),
+		Explain:         true,
+		ExplainFile:     explainFile,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+	trace := string(content)
+
+	assert.Contains(t, trace, filepath.Join(tempDir, "abc/one.txt")+`
+  exclude **/*.yml: no match
+  include *.txt: match
+  include **/one.*: match
+  verdict: included, include pattern *.txt matched
+`)
+	assert.Contains(t, trace, filepath.Join(tempDir, "abc/one.yml")+`
+  exclude **/*.yml: match
+  include *.txt: no match
+  include **/one.*: match
+  verdict: excluded, exclude pattern **/*.yml matched
+`)
+	assert.Contains(t, trace, filepath.Join(tempDir, "abc/def/two.txt")+`
+  exclude **/*.yml: no match
+  include *.txt: no match
+  include **/one.*: no match
+  verdict: excluded, no include pattern matched
+`)
+}
+
+func Test_Exec_Explain_Gitignore(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("def/\n"), 0644))
+
+	explainFile := filepath.Join(tempDir, "explain.txt")
+	args := Args{
+		Filter:      "**/*.txt",
+		Gitignore:   true,
+		TargetDir:   tempDir,
+		Explain:     true,
+		ExplainFile: explainFile,
+	}
+
+	_, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+
+	assert.Contains(t, string(content), filepath.Join(tempDir, "abc/def")+`
+  gitignore `+filepath.Join(tempDir, ".gitignore")+`:1 def/: match
+  verdict: excluded, ignored by git, directory not descended into
+`)
+	assert.NotContains(t, string(content), filepath.Join(tempDir, "abc/def/one.txt"))
+}
+
+func Test_Exec_Explain_Dockerignore(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	dockerignore := filepath.Join(tempDir, ".dockerignore")
+	fatalIf(os.WriteFile(dockerignore, []byte("*.xyz\n!a.xyz\n.dockerignore\n"), 0644))
+
+	explainFile := filepath.Join(tempDir, "abc", "explain.txt")
+	args := Args{
+		DockerContext: true,
+		TargetDir:     tempDir,
+		Explain:       true,
+		ExplainFile:   explainFile,
+	}
+
+	_, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+	trace := string(content)
+
+	assert.Contains(t, trace, filepath.Join(tempDir, "b.xyz")+`
+  dockerignore `+dockerignore+`:1 *.xyz: match
+  verdict: excluded, ignored by `+dockerignore+`
+`)
+	assert.Contains(t, trace, filepath.Join(tempDir, "a.xyz")+`
+  dockerignore `+dockerignore+`:2 !a.xyz: match
+`)
+	assert.Contains(t, trace, filepath.Join(tempDir, ".dockerignore")+`
+  dockerignore !.dockerignore: match
+`)
+	assert.Contains(t, trace, filepath.Join(tempDir, "abc")+`
+  dockerignore: no match
+`)
+}
+
+func Test_Exec_Explain_SkipsTraceFile(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	explainFile := tempDir + "/abc/def/../explain.txt"
+	args := Args{
+		Filter:      "*.txt",
+		TargetDir:   filepath.Join(tempDir, "abc"),
+		Explain:     true,
+		ExplainFile: explainFile,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	for _, file := range files {
+		assert.NotEqual(t, "explain.txt", file.Name)
+	}
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+	assert.NotContains(t, string(content), "explain.txt")
+}
@@ -0,0 +1,264 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignore applies the rules of the .gitignore files discovered
+// while walking a directory tree, plus the repository's
+// .git/info/exclude file, following the semantics described in
+// gitignore(5).
+type gitignore struct {
+	// rules holds the rules of each loaded .gitignore file keyed
+	// by its directory, relative to the search root.
+	rules map[string][]ignoreRule
+
+	// excludes holds the rules of .git/info/exclude, which have
+	// lower precedence than any .gitignore file.
+	excludes []ignoreRule
+}
+
+// ignoreRule is a single parsed gitignore pattern, with the file
+// and line it was read from.
+type ignoreRule struct {
+	text    string
+	file    string
+	line    int
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// newGitignore returns a gitignore for the search root, loading
+// .git/info/exclude when the root holds a .git directory.
+func newGitignore(root string) (*gitignore, error) {
+	g := &gitignore{rules: map[string][]ignoreRule{}}
+
+	if fi, err := os.Stat(filepath.Join(root, ".git")); err != nil || !fi.IsDir() {
+		return g, nil
+	}
+	excludes, err := readIgnoreFile(filepath.Join(root, ".git", "info", "exclude"))
+	if err != nil {
+		return nil, err
+	}
+	g.excludes = excludes
+	return g, nil
+}
+
+// load reads the .gitignore file of a directory, given by its
+// path relative to the search root, if one exists.
+func (g *gitignore) load(root, dir string) error {
+	rules, err := readIgnoreFile(filepath.Join(root, filepath.FromSlash(dir), ".gitignore"))
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		g.rules[dir] = rules
+	}
+	return nil
+}
+
+// gitMetadataRule is reported when a path is ignored because it is
+// git's own metadata directory rather than because of a rule.
+var gitMetadataRule = &ignoreRule{text: ".git"}
+
+// ignored reports whether a path relative to the search root is
+// ignored, along with the rule that decided it, or nil when no rule
+// matched. Rules of deeper .gitignore files take precedence over
+// rules of their parents and, within a file, the last matching
+// rule wins.
+func (g *gitignore) ignored(rel string, isDir bool) (bool, *ignoreRule) {
+	// git never tracks its own metadata directory.
+	if path.Base(rel) == ".git" {
+		return true, gitMetadataRule
+	}
+
+	for dir := path.Dir(rel); ; dir = path.Dir(dir) {
+		if rules, ok := g.rules[dir]; ok {
+			name := rel
+			if dir != "." {
+				name = strings.TrimPrefix(rel, dir+"/")
+			}
+			if rule := matchIgnoreRules(rules, name, isDir); rule != nil {
+				return !rule.negate, rule
+			}
+		}
+		if dir == "." {
+			break
+		}
+	}
+
+	if rule := matchIgnoreRules(g.excludes, rel, isDir); rule != nil {
+		return !rule.negate, rule
+	}
+	return false, nil
+}
+
+// matchIgnoreRules evaluates the rules from last to first and
+// returns the first one matching the name, or nil when none does.
+func matchIgnoreRules(rules []ignoreRule, name string, isDir bool) *ignoreRule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := &rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(name) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// readIgnoreFile parses an ignore file, returning no rules when
+// the file does not exist.
+func readIgnoreFile(name string) ([]ignoreRule, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ignore file %s: %w", name, err)
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			rule.file, rule.line = name, line
+			rules = append(rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore file %s: %w", name, err)
+	}
+	return rules, nil
+}
+
+// parseIgnoreRule parses a line of a gitignore file. It returns
+// false for blank lines and comments.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimUnescapedSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{text: line}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// a separator at the beginning or in the middle anchors the
+	// pattern to the directory of the .gitignore file, otherwise
+	// it matches at any level below it.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := "^"
+	if !anchored {
+		expr += "(?:.*/)?"
+	}
+	rule.re = regexp.MustCompile(expr + translateIgnorePattern(line) + "$")
+	return rule, true
+}
+
+// trimUnescapedSpaces removes trailing spaces that are not
+// escaped with a backslash.
+func trimUnescapedSpaces(s string) string {
+	for strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\\ ") {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// translateIgnorePattern translates a gitignore pattern into a
+// regular expression.
+func translateIgnorePattern(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case i == 0 && strings.HasPrefix(pattern, "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "/**/"):
+			b.WriteString("/(?:.*/)?")
+			i += 3
+		case pattern[i:] == "/**":
+			b.WriteString("/.+")
+			i += 2
+		case c == '*':
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+			}
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			class, n := translateIgnoreClass(pattern[i:])
+			if n == 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(class)
+			i += n - 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// translateIgnoreClass translates the bracket expression at the
+// start of s into a regular expression class. It returns the
+// class and the number of bytes consumed, or zero when the
+// bracket is not terminated.
+func translateIgnoreClass(s string) (string, int) {
+	var b strings.Builder
+	b.WriteString("[")
+	i := 1
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		b.WriteString("^/")
+		i++
+	}
+	for first := true; i < len(s); first = false {
+		c := s[i]
+		switch {
+		case c == ']' && !first:
+			b.WriteString("]")
+			return b.String(), i + 1
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		case c == '-':
+			b.WriteString("-")
+		default:
+			b.WriteString(regexp.QuoteMeta(s[i : i+1]))
+		}
+		i++
+	}
+	return "", 0
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		line    string
+		path    string
+		isDir   bool
+		matched bool
+	}{
+		{line: "*.log", path: "debug.log", matched: true},
+		{line: "*.log", path: "logs/debug.log", matched: true},
+		{line: "/*.log", path: "logs/debug.log", matched: false},
+		{line: "/*.log", path: "debug.log", matched: true},
+		{line: "build/", path: "build", isDir: true, matched: true},
+		{line: "build/", path: "build", isDir: false, matched: false},
+		{line: "build/", path: "src/build", isDir: true, matched: true},
+		{line: "doc/frotz", path: "doc/frotz", matched: true},
+		{line: "doc/frotz", path: "a/doc/frotz", matched: false},
+		{line: "**/foo", path: "a/b/foo", matched: true},
+		{line: "**/foo/bar", path: "foo/bar", matched: true},
+		{line: "abc/**", path: "abc/x/y", matched: true},
+		{line: "abc/**", path: "abc", isDir: true, matched: false},
+		{line: "a/**/b", path: "a/b", matched: true},
+		{line: "a/**/b", path: "a/x/y/b", matched: true},
+		{line: "foo?.txt", path: "foo1.txt", matched: true},
+		{line: "foo?.txt", path: "foo/.txt", matched: false},
+		{line: "file[0-9].txt", path: "file7.txt", matched: true},
+		{line: "file[!0-9].txt", path: "file7.txt", matched: false},
+		{line: "file[!0-9].txt", path: "filex.txt", matched: true},
+		{line: `\#notes`, path: "#notes", matched: true},
+		{line: `\!important`, path: "!important", matched: true},
+		{line: `trailing\ `, path: "trailing ", matched: true},
+		{line: "trailing   ", path: "trailing", matched: true},
+	}
+	for _, test := range tests {
+		rule, ok := parseIgnoreRule(test.line)
+		assert.True(t, ok, test.line)
+
+		matched := matchIgnoreRules([]ignoreRule{rule}, test.path, test.isDir) != nil
+		assert.Equal(t, test.matched, matched, "%q against %q", test.line, test.path)
+	}
+}
+
+func Test_parseIgnoreRule_Skipped(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "/"} {
+		_, ok := parseIgnoreRule(line)
+		assert.False(t, ok, line)
+	}
+}
+
+func Test_gitignore_Ignored(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "findfiles")
+	fatalIf(err)
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.MkdirAll(filepath.Join(tempDir, ".git/info"), 0755))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, ".git/info/exclude"), []byte("*.tmp\n"), 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte("*.log\n!keep.log\n/dist/\n"), 0644))
+	fatalIf(os.MkdirAll(filepath.Join(tempDir, "sub"), 0755))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "sub/.gitignore"), []byte("!*.log\nkeep.log\n*.tmp\n!local.tmp\n"), 0644))
+
+	g, err := newGitignore(tempDir)
+	fatalIf(err)
+	fatalIf(g.load(tempDir, "."))
+	fatalIf(g.load(tempDir, "sub"))
+
+	root := filepath.Join(tempDir, ".gitignore")
+	sub := filepath.Join(tempDir, "sub/.gitignore")
+	exclude := filepath.Join(tempDir, ".git/info/exclude")
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+		rule    string
+	}{
+		{path: ".git", isDir: true, ignored: true, rule: ".git"},
+		{path: "debug.log", ignored: true, rule: root + ":1 *.log"},
+		{path: "keep.log", ignored: false, rule: root + ":2 !keep.log"},
+		{path: "dist", isDir: true, ignored: true, rule: root + ":3 /dist/"},
+		{path: "sub/dist", isDir: true, ignored: false},
+		{path: "cache.tmp", ignored: true, rule: exclude + ":1 *.tmp"},
+		{path: "sub/debug.log", ignored: false, rule: sub + ":1 !*.log"},
+		{path: "sub/keep.log", ignored: true, rule: sub + ":2 keep.log"},
+		{path: "sub/cache.tmp", ignored: true, rule: sub + ":3 *.tmp"},
+		{path: "sub/local.tmp", ignored: false, rule: sub + ":4 !local.tmp"},
+	}
+	for _, test := range tests {
+		ignored, rule := g.ignored(test.path, test.isDir)
+		assert.Equal(t, test.ignored, ignored, test.path)
+		if test.rule == "" {
+			assert.Nil(t, rule, test.path)
+			continue
+		}
+		if assert.NotNil(t, rule, test.path) {
+			assert.Equal(t, test.rule, ruleSource(rule.file, rule.line, rule.text), test.path)
+		}
+	}
+}
+
+func Test_Exec_Absolute_Gitignore(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.MkdirAll(filepath.Join(tempDir, ".git/info"), 0755))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, ".git/info/exclude"), []byte("two.txt\n"), 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, ".git/notes.txt"), []byte{}, 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/.gitignore"), []byte("def/\n"), 0644))
+
+	args := Args{
+		Filter:    "**/*.txt",
+		Gitignore: true,
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, filepath.Join(tempDir, "abc/one.txt"), files[0].Path)
+}
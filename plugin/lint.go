@@ -0,0 +1,199 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// lintIssue describes a pattern that can never match, pointing to
+// the token of the pattern at fault.
+type lintIssue struct {
+	kind    string
+	pattern string
+	offset  int
+	token   string
+	message string
+}
+
+func (i lintIssue) Error() string {
+	return fmt.Sprintf("%s pattern %q: %s (token %q at offset %d)", i.kind, i.pattern, i.message, i.token, i.offset)
+}
+
+// lintPatterns statically checks the include and exclude patterns
+// and rules against the configured search directory. Combinations
+// that can never match are returned as an error, and combinations
+// that are likely mistakes are returned as warnings.
+func lintPatterns(args Args, includes, excludes []pattern, rules []rule) (warnings []string, err error) {
+	mode := args.MatchMode
+	if mode == "" {
+		mode = defaultMatchMode
+	}
+	// regular expressions are not made of path segments, and
+	// gitignore or custom patterns follow their own anchoring rules.
+	if args.Compiler != nil || (mode != "ant" && mode != "glob") {
+		return nil, nil
+	}
+
+	check := func(kind, text string) error {
+		if i := strings.Index(text, "//"); i >= 0 {
+			issue := lintIssue{
+				kind:    kind,
+				pattern: text,
+				offset:  i,
+				token:   "//",
+				message: "empty path segment",
+			}
+			// glob patterns match the separators literally, while
+			// Ant patterns ignore empty segments.
+			if mode == "glob" {
+				return issue
+			}
+			issue.message += " ignored by Ant patterns"
+			warnings = append(warnings, issue.Error())
+		}
+		if issue, ok := lintRoots(kind, text, args); ok {
+			return issue
+		}
+		return nil
+	}
+
+	for _, p := range includes {
+		if err := check("include", p.text); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range excludes {
+		if err := check("exclude", p.text); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range rules {
+		kind := "exclude"
+		if r.include {
+			kind = "include"
+		}
+		if err := check(kind, r.text); err != nil {
+			return nil, err
+		}
+	}
+
+	// an include rule may bring back paths dropped by an exclude
+	// pattern.
+	if len(includes) > 0 && !hasInclude(rules) {
+		if exclude, ok := swallowingExclude(mode, includes, excludes); ok {
+			warnings = append(warnings, fmt.Sprintf("exclude pattern %q matches every path matched by the include patterns, the search will not return any file", exclude))
+		}
+	}
+	return warnings, nil
+}
+
+// lintRoots checks a pattern against every search directory, and only
+// reports an issue when the pattern can match under none of them.
+func lintRoots(kind, text string, args Args) (lintIssue, bool) {
+	var first lintIssue
+	for i, root := range searchRoots(args.TargetDir) {
+		rootArgs := args
+		rootArgs.TargetDir = root
+		issue, ok := lintPattern(kind, text, rootArgs)
+		if !ok {
+			return lintIssue{}, false
+		}
+		if i == 0 {
+			first = issue
+		}
+	}
+	return first, true
+}
+
+// lintPattern checks a single pattern for a mismatch between an
+// absolute or relative pattern and the paths it is matched against.
+func lintPattern(kind, text string, args Args) (lintIssue, bool) {
+	dir := args.TargetDir
+	absolute := strings.HasPrefix(text, "/")
+
+	switch {
+	case args.PatternBase == patternBaseFull && filepath.IsAbs(dir) && !absolute:
+		return lintIssue{
+			kind:    kind,
+			pattern: text,
+			token:   firstSegment(text),
+			message: fmt.Sprintf("relative pattern can never match the absolute paths under %q, use an absolute pattern or pattern base %s", dir, patternBaseDir),
+		}, true
+	case args.PatternBase == patternBaseFull && !filepath.IsAbs(dir) && absolute:
+		return lintIssue{
+			kind:    kind,
+			pattern: text,
+			token:   "/",
+			message: fmt.Sprintf("absolute pattern can never match the relative paths under %q", dir),
+		}, true
+	case args.PatternBase != patternBaseFull && absolute:
+		return lintIssue{
+			kind:    kind,
+			pattern: text,
+			token:   "/",
+			message: fmt.Sprintf("absolute pattern can never match paths relative to %q, use a relative pattern or pattern base %s", dir, patternBaseFull),
+		}, true
+	}
+	return lintIssue{}, false
+}
+
+// firstSegment returns the first path segment of a pattern.
+func firstSegment(text string) string {
+	if i := strings.Index(text, "/"); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
+
+// swallowingExclude returns an exclude pattern matching every path
+// the include patterns can match. Include patterns are matched
+// against the exclude patterns as if they were paths, so wildcards
+// of an include pattern are only covered by the same or broader
+// wildcards of the exclude pattern.
+func swallowingExclude(mode string, includes, excludes []pattern) (string, bool) {
+	for _, exclude := range excludes {
+		swallowed := true
+		for _, include := range includes {
+			for _, variant := range includeVariants(mode, include.text) {
+				if !exclude.Match(variant) {
+					swallowed = false
+				}
+			}
+		}
+		if swallowed {
+			return exclude.text, true
+		}
+	}
+	return "", false
+}
+
+// includeVariants returns the paths an include pattern is tested
+// with. For Ant patterns, ** segments are replaced by zero, one and
+// two wildcard segments, so that only patterns with a ** segment at
+// the same place can match all of them.
+func includeVariants(mode, text string) []string {
+	if mode != "ant" {
+		return []string{text}
+	}
+	var variants []string
+	for _, replacement := range []string{"", "**", "**/**"} {
+		var segments []string
+		for _, segment := range strings.Split(text, "/") {
+			if segment == "**" {
+				if replacement == "" {
+					continue
+				}
+				segment = replacement
+			}
+			segments = append(segments, segment)
+		}
+		variants = append(variants, strings.Join(segments, "/"))
+	}
+	return variants
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lint(args Args) ([]string, error) {
+	includes, excludes, err := loadPatterns(args)
+	fatalIf(err)
+	rules, err := parseRules(args)
+	fatalIf(err)
+	return lintPatterns(args, includes, excludes, rules)
+}
+
+func Test_lintPatterns_Errors(t *testing.T) {
+	tests := []struct {
+		args Args
+		err  string
+	}{
+		{
+			args: Args{Filter: "src//*.go", MatchMode: "glob"},
+			err:  `include pattern "src//*.go": empty path segment (token "//" at offset 3)`,
+		},
+		{
+			args: Args{Filter: "**/*.go", Excludes: "**/vendor//*", MatchMode: "glob"},
+			err:  `exclude pattern "**/vendor//*": empty path segment (token "//" at offset 9)`,
+		},
+		{
+			args: Args{Filter: "src/**/*.go", TargetDir: "/harness", PatternBase: "full"},
+			err:  `include pattern "src/**/*.go": relative pattern can never match the absolute paths under "/harness", use an absolute pattern or pattern base dir (token "src" at offset 0)`,
+		},
+		{
+			args: Args{Filter: "src/**/*.go", TargetDir: "/harness,/drone", PatternBase: "full"},
+			err:  `include pattern "src/**/*.go": relative pattern can never match the absolute paths under "/harness", use an absolute pattern or pattern base dir (token "src" at offset 0)`,
+		},
+		{
+			args: Args{Filter: "/**/*.go", TargetDir: "src", PatternBase: "full"},
+			err:  `include pattern "/**/*.go": absolute pattern can never match the relative paths under "src" (token "/" at offset 0)`,
+		},
+		{
+			args: Args{Filter: "/**/*.go", TargetDir: "/harness"},
+			err:  `include pattern "/**/*.go": absolute pattern can never match paths relative to "/harness", use a relative pattern or pattern base full (token "/" at offset 0)`,
+		},
+	}
+	for _, test := range tests {
+		_, err := lint(test.args)
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func Test_lintPatterns_Valid(t *testing.T) {
+	tests := []Args{
+		{Filter: "src/**/*.go", TargetDir: "/harness"},
+		{Filter: "/**/*.go", TargetDir: "/harness", PatternBase: "full"},
+		{Filter: "src/**/*.go", PatternBase: "full"},
+		{Filter: "/**/*.go", TargetDir: "/harness,src", PatternBase: "full"},
+		{Filter: "^/harness//", TargetDir: "/harness", MatchMode: "regex"},
+	}
+	for _, args := range tests {
+		warnings, err := lint(args)
+		assert.NoError(t, err, args.Filter)
+		assert.Empty(t, warnings, args.Filter)
+	}
+}
+
+func Test_lintPatterns_AntEmptySegment(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{Filter: "abc//def/*.txt", TargetDir: tempDir}
+	warnings, err := lint(args)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`include pattern "abc//def/*.txt": empty path segment ignored by Ant patterns (token "//" at offset 3)`}, warnings)
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func Test_lintPatterns_SwallowingExclude(t *testing.T) {
+	tests := []struct {
+		filter   string
+		excludes string
+		mode     string
+		warning  bool
+	}{
+		{filter: "src/**/*.go", excludes: "**/*.go", warning: true},
+		{filter: "src/**/*.go,*.go", excludes: "**", warning: true},
+		{filter: "src/**/*.go", excludes: "src/**", warning: true},
+		{filter: "**/*.txt", excludes: "**/*.txt", warning: true},
+		{filter: "src/**/*.go,**/*.txt", excludes: "**/*.go", warning: false},
+		{filter: "abc/**", excludes: "abc/*", warning: false},
+		{filter: "abc/**", excludes: "abc/*/*", warning: false},
+		{filter: "src/*.go", excludes: "src/*", mode: "glob", warning: true},
+		{filter: "src/*.go", excludes: "src/*.txt", mode: "glob", warning: false},
+	}
+	for _, test := range tests {
+		warnings, err := lint(Args{Filter: test.filter, Excludes: test.excludes, MatchMode: test.mode})
+		assert.NoError(t, err)
+		if test.warning {
+			assert.Len(t, warnings, 1, "%q excluding %q", test.filter, test.excludes)
+		} else {
+			assert.Empty(t, warnings, "%q excluding %q", test.filter, test.excludes)
+		}
+	}
+}
+
+func Test_validateArg_LintError(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	err := validateArgs(Args{
+		Filter:      "src/**/*.go",
+		TargetDir:   "/harness",
+		PatternBase: "full",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,273 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultMatchMode is the match mode used when none is configured.
+const defaultMatchMode = "ant"
+
+// customMatchMode names the matcher of a search using a Compiler
+// passed by a library caller.
+const customMatchMode = "custom"
+
+// Matcher reports whether a path matches a compiled pattern.
+type Matcher interface {
+	Match(path string) bool
+}
+
+// MatchOptions configures how patterns are compiled.
+type MatchOptions struct {
+	// CaseInsensitive makes patterns match paths regardless of
+	// their case.
+	CaseInsensitive bool
+}
+
+// Compiler compiles a pattern into a Matcher.
+type Compiler func(pattern string, opts MatchOptions) (Matcher, error)
+
+var (
+	matchModesMu sync.RWMutex
+
+	// matchModes maps the PLUGIN_MATCH_MODE values to their
+	// pattern compilers.
+	matchModes = map[string]Compiler{
+		"ant":       compileAnt,
+		"gitignore": compileGitignore,
+		"glob":      compileGlob,
+		"regex":     compileRegex,
+	}
+)
+
+// RegisterMatcher makes a pattern compiler available under the given
+// match mode name. It panics if the name is empty, already registered,
+// or if compile is nil.
+func RegisterMatcher(name string, compile Compiler) {
+	matchModesMu.Lock()
+	defer matchModesMu.Unlock()
+
+	if name == "" || name == customMatchMode {
+		panic(fmt.Sprintf("plugin: invalid match mode name %q", name))
+	}
+	if compile == nil {
+		panic("plugin: RegisterMatcher compiler is nil")
+	}
+	if _, dup := matchModes[name]; dup {
+		panic("plugin: RegisterMatcher called twice for match mode " + name)
+	}
+	matchModes[name] = compile
+}
+
+// lookupMatcher returns the pattern compiler registered for a match mode.
+func lookupMatcher(mode string) (Compiler, bool) {
+	matchModesMu.RLock()
+	defer matchModesMu.RUnlock()
+
+	compile, ok := matchModes[mode]
+	return compile, ok
+}
+
+// pattern is a compiled include or exclude pattern.
+type pattern struct {
+	text string
+	Matcher
+}
+
+// compileFor compiles patterns with the Compiler of the arguments or,
+// when none is set, with the one registered for their match mode.
+func compileFor(args Args, patterns []string) ([]pattern, error) {
+	mode, compile := customMatchMode, args.Compiler
+	if compile == nil {
+		var err error
+		if mode, compile, err = lookupMode(args.MatchMode); err != nil {
+			return nil, err
+		}
+	}
+	return compileWith(mode, normalizeCompiler(args.UnicodeNormalization, compile), patterns, matchOptionsFor(args))
+}
+
+// compilePatterns compiles the patterns using the given match mode.
+func compilePatterns(mode string, patterns []string, opts MatchOptions) ([]pattern, error) {
+	mode, compile, err := lookupMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	return compileWith(mode, compile, patterns, opts)
+}
+
+// lookupMode returns the compiler of a match mode, along with the
+// name of the mode, which defaults to ant.
+func lookupMode(mode string) (string, Compiler, error) {
+	if mode == "" {
+		mode = defaultMatchMode
+	}
+	compile, ok := lookupMatcher(mode)
+	if !ok {
+		return "", nil, fmt.Errorf("unknown match mode %q, expected one of %s", mode, strings.Join(matchModeNames(), ", "))
+	}
+	return mode, compile, nil
+}
+
+func compileWith(mode string, compile Compiler, patterns []string, opts MatchOptions) ([]pattern, error) {
+	var compiled []pattern
+	for _, text := range patterns {
+		m, err := compile(text, opts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", mode, text, err)
+		}
+		compiled = append(compiled, pattern{text: text, Matcher: m})
+	}
+	return compiled, nil
+}
+
+// matchModeNames returns the sorted names of the registered match modes.
+func matchModeNames() []string {
+	matchModesMu.RLock()
+	defer matchModesMu.RUnlock()
+
+	var names []string
+	for name := range matchModes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// antMatcher matches Ant style patterns, extended with brace
+// alternatives and character classes.
+type antMatcher struct {
+	alternatives []antPattern
+	fold         bool
+}
+
+func compileAnt(pattern string, opts MatchOptions) (Matcher, error) {
+	if opts.CaseInsensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	m := antMatcher{fold: opts.CaseInsensitive}
+	for _, expanded := range expandBraces(pattern) {
+		p, err := parseAntPattern(expanded)
+		if err != nil {
+			return nil, err
+		}
+		m.alternatives = append(m.alternatives, p)
+	}
+	return m, nil
+}
+
+func (a antMatcher) Match(path string) bool {
+	if a.fold {
+		path = strings.ToLower(path)
+	}
+	for _, p := range a.alternatives {
+		if p.match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatcher matches shell file name patterns as implemented by
+// path.Match, extended with brace alternatives. Paths are always slash
+// separated, so path.Match keeps / as the separator and \ as the
+// escape character on every platform, where filepath.Match would use
+// \ for both on Windows.
+type globMatcher struct {
+	alternatives []string
+	fold         bool
+}
+
+func compileGlob(pattern string, opts MatchOptions) (Matcher, error) {
+	if opts.CaseInsensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	m := globMatcher{fold: opts.CaseInsensitive}
+	for _, expanded := range expandBraces(pattern) {
+		// path.Match reports malformed patterns even when the
+		// name does not match, which validates the whole pattern.
+		if _, err := path.Match(expanded, ""); err != nil {
+			return nil, err
+		}
+		m.alternatives = append(m.alternatives, expanded)
+	}
+	return m, nil
+}
+
+func (g globMatcher) Match(name string) bool {
+	if g.fold {
+		name = strings.ToLower(name)
+	}
+	for _, pattern := range g.alternatives {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// regexMatcher matches regular expressions anywhere in the path.
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func compileRegex(pattern string, opts MatchOptions) (Matcher, error) {
+	if opts.CaseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return regexMatcher{re: re}, nil
+}
+
+func (r regexMatcher) Match(path string) bool {
+	return r.re.MatchString(path)
+}
+
+// gitignoreMatcher matches gitignore patterns. Like git, a pattern
+// matching a directory also matches everything below it, and a
+// pattern with a trailing slash only matches below a directory.
+type gitignoreMatcher struct {
+	rule ignoreRule
+	fold bool
+}
+
+func compileGitignore(pattern string, opts MatchOptions) (Matcher, error) {
+	if opts.CaseInsensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	rule, ok := parseIgnoreRule(pattern)
+	if !ok {
+		return nil, errors.New("empty pattern")
+	}
+	if rule.negate {
+		return nil, errors.New("negated patterns are not supported, use an exclude pattern")
+	}
+	return gitignoreMatcher{rule: rule, fold: opts.CaseInsensitive}, nil
+}
+
+func (g gitignoreMatcher) Match(p string) bool {
+	if g.fold {
+		p = strings.ToLower(p)
+	}
+	if !g.rule.dirOnly && g.rule.re.MatchString(p) {
+		return true
+	}
+	for dir := path.Dir(p); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if g.rule.re.MatchString(dir) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,283 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compilePatterns_UnknownMode(t *testing.T) {
+	_, err := compilePatterns("wildcard", []string{"**/*.txt"}, MatchOptions{})
+
+	assert.EqualError(t, err, `unknown match mode "wildcard", expected one of ant, gitignore, glob, regex`)
+}
+
+func Test_compilePatterns_DefaultMode(t *testing.T) {
+	patterns, err := compilePatterns("", []string{"**/*.txt"}, MatchOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, patterns, 1)
+
+	assert.True(t, patterns[0].Match("abc/def/one.txt"))
+	assert.False(t, patterns[0].Match("abc/def/one.yml"))
+}
+
+func Test_compilePatterns_Regex(t *testing.T) {
+	patterns, err := compilePatterns("regex", []string{`release-(\d+)\.(\d+)\.tar\.gz$`, `^charts/[^/]+/[^/]+$`}, MatchOptions{})
+	assert.NoError(t, err)
+
+	assert.True(t, patterns[0].Match("dist/release-1.12.tar.gz"))
+	assert.False(t, patterns[0].Match("dist/release-1.x.tar.gz"))
+	assert.True(t, patterns[1].Match("charts/app/values.yaml"))
+	assert.False(t, patterns[1].Match("charts/app/templates/service.yaml"))
+}
+
+func Test_compilePatterns_InvalidRegex(t *testing.T) {
+	_, err := compilePatterns("regex", []string{"release-(\\d+"}, MatchOptions{})
+
+	assert.EqualError(t, err, "invalid regex pattern \"release-(\\\\d+\": error parsing regexp: missing closing ): `release-(\\d+`")
+}
+
+func Test_compilePatterns_Glob(t *testing.T) {
+	patterns, err := compilePatterns("glob", []string{"abc/*.txt"}, MatchOptions{})
+	assert.NoError(t, err)
+
+	assert.True(t, patterns[0].Match("abc/one.txt"))
+	assert.False(t, patterns[0].Match("abc/def/one.txt"))
+}
+
+func Test_compilePatterns_GlobSeparator(t *testing.T) {
+	patterns, err := compilePatterns("glob", []string{"src/*.go", `out/\*.txt`}, MatchOptions{})
+	assert.NoError(t, err)
+
+	// * does not cross / and \ escapes on every platform.
+	assert.True(t, patterns[0].Match("src/a.go"))
+	assert.False(t, patterns[0].Match("src/a/b.go"))
+	assert.True(t, patterns[1].Match("out/*.txt"))
+	assert.False(t, patterns[1].Match("out/one.txt"))
+}
+
+func Test_compilePatterns_InvalidGlob(t *testing.T) {
+	_, err := compilePatterns("glob", []string{"abc/[a-"}, MatchOptions{})
+
+	assert.EqualError(t, err, `invalid glob pattern "abc/[a-": syntax error in pattern`)
+}
+
+func Test_validateArg_InvalidExclude(t *testing.T) {
+	err := validateArgs(Args{
+		Filter:    "abc/*.txt",
+		Excludes:  "abc/[",
+		MatchMode: "glob",
+	})
+	assert.EqualError(t, err, `invalid glob pattern "abc/[": syntax error in pattern`)
+}
+
+func Test_Exec_Relative_RegexMode(t *testing.T) {
+	setupRelativeFilesAndFolders()
+	defer cleanupRelativeFilesAndFolders()
+
+	args := Args{
+		Filter:    `^abc/def/one\.`,
+		Excludes:  `\.txt$`,
+		MatchMode: "regex",
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, "abc/def/one.yml")
+	assert.Contains(t, paths, "abc/def/one.xml")
+}
+
+func Test_Exec_Relative_GlobMode(t *testing.T) {
+	setupRelativeFilesAndFolders()
+	defer cleanupRelativeFilesAndFolders()
+
+	args := Args{
+		Filter:    "abc/*.txt",
+		MatchMode: "glob",
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, "abc/one.txt")
+	assert.Contains(t, paths, "abc/two.txt")
+}
+
+func Test_compilePatterns_CaseInsensitive(t *testing.T) {
+	tests := []struct {
+		mode    string
+		pattern string
+	}{
+		{mode: "ant", pattern: "**/*.xml"},
+		{mode: "ant", pattern: "**/REPORT.XML"},
+		{mode: "glob", pattern: "out/*.[x]ml"},
+		{mode: "regex", pattern: `\.xml$`},
+		{mode: "gitignore", pattern: "*.XML"},
+	}
+	for _, test := range tests {
+		patterns, err := compilePatterns(test.mode, []string{test.pattern}, MatchOptions{CaseInsensitive: true})
+		assert.NoError(t, err)
+
+		for _, path := range []string{"out/Report.XML", "out/report.xml", "out/REPORT.Xml"} {
+			assert.True(t, patterns[0].Match(path), "%s pattern %q against %q", test.mode, test.pattern, path)
+		}
+		assert.False(t, patterns[0].Match("out/report.json"), "%s pattern %q", test.mode, test.pattern)
+	}
+}
+
+func Test_Exec_Absolute_CaseInsensitive(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/Report.XML"), []byte{}, 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/REPORT.Xml"), []byte{}, 0644))
+
+	args := Args{
+		Filter:          "**/*.xml",
+		Excludes:        "**/DEF/**",
+		CaseInsensitive: true,
+		TargetDir:       tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/Report.XML"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/REPORT.Xml"))
+}
+
+func Test_compilePatterns_GlobBraces(t *testing.T) {
+	patterns, err := compilePatterns("glob", []string{"abc/*.{yml,txt}"}, MatchOptions{})
+	assert.NoError(t, err)
+
+	assert.True(t, patterns[0].Match("abc/one.txt"))
+	assert.True(t, patterns[0].Match("abc/one.yml"))
+	assert.False(t, patterns[0].Match("abc/one.xml"))
+}
+
+func Test_compilePatterns_Gitignore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{
+			pattern: "*.log",
+			match:   []string{"debug.log", "abc/debug.log", "logs.log/trace.txt"},
+			noMatch: []string{"debug.log.txt", "abc/debug.txt"},
+		},
+		{
+			pattern: "/build",
+			match:   []string{"build", "build/out/app"},
+			noMatch: []string{"abc/build", "builder"},
+		},
+		{
+			pattern: "docs/**/*.md",
+			match:   []string{"docs/a.md", "docs/x/y/a.md"},
+			noMatch: []string{"abc/docs/a.md", "docs/a.txt"},
+		},
+		{
+			pattern: "vendor/",
+			match:   []string{"vendor/lib.go", "abc/vendor/x/lib.go"},
+			noMatch: []string{"vendor", "abc/vendor"},
+		},
+	}
+	for _, test := range tests {
+		patterns, err := compilePatterns("gitignore", []string{test.pattern}, MatchOptions{})
+		assert.NoError(t, err)
+		for _, path := range test.match {
+			assert.True(t, patterns[0].Match(path), "pattern %q against %q", test.pattern, path)
+		}
+		for _, path := range test.noMatch {
+			assert.False(t, patterns[0].Match(path), "pattern %q against %q", test.pattern, path)
+		}
+	}
+}
+
+func Test_compilePatterns_InvalidGitignore(t *testing.T) {
+	_, err := compilePatterns("gitignore", []string{"!*.log"}, MatchOptions{})
+	assert.EqualError(t, err, `invalid gitignore pattern "!*.log": negated patterns are not supported, use an exclude pattern`)
+
+	_, err = compilePatterns("gitignore", []string{"# comment"}, MatchOptions{})
+	assert.EqualError(t, err, `invalid gitignore pattern "# comment": empty pattern`)
+}
+
+// suffixMatcher matches paths ending with a suffix.
+type suffixMatcher string
+
+func (m suffixMatcher) Match(path string) bool {
+	return strings.HasSuffix(path, string(m))
+}
+
+func compileSuffix(pattern string, opts MatchOptions) (Matcher, error) {
+	if pattern == "" {
+		return nil, errors.New("empty suffix")
+	}
+	return suffixMatcher(pattern), nil
+}
+
+func Test_RegisterMatcher(t *testing.T) {
+	RegisterMatcher("suffix-test", compileSuffix)
+	assert.Contains(t, matchModeNames(), "suffix-test")
+
+	patterns, err := compilePatterns("suffix-test", []string{".yml"}, MatchOptions{})
+	assert.NoError(t, err)
+	assert.True(t, patterns[0].Match("abc/one.yml"))
+	assert.False(t, patterns[0].Match("abc/one.txt"))
+
+	assert.Panics(t, func() { RegisterMatcher("suffix-test", compileSuffix) })
+	assert.Panics(t, func() { RegisterMatcher("", compileSuffix) })
+	assert.Panics(t, func() { RegisterMatcher("nil-test", nil) })
+}
+
+func Test_Exec_CustomCompiler(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:    ".txt",
+		Excludes:  "two.txt",
+		Compiler:  compileSuffix,
+		TargetDir: filepath.Join(tempDir, "abc"),
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/one.txt"))
+}
+
+func Test_compileFor_CustomCompilerError(t *testing.T) {
+	_, err := compileFor(Args{Compiler: compileSuffix}, []string{""})
+	assert.EqualError(t, err, `invalid custom pattern "": empty suffix`)
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Path styles select the separator of the paths written to the output.
+const (
+	pathStyleNative = "native"
+	pathStylePosix  = "posix"
+)
+
+// slashPath converts a path using the given separator to a slash
+// separated path, which is the form every pattern is matched against.
+func slashPath(p string, sep byte) string {
+	if sep == '/' {
+		return p
+	}
+	return strings.ReplaceAll(p, string(sep), "/")
+}
+
+// formatPath formats a path using the given separator for the output.
+// The posix style separates the segments with slashes, while the
+// native style consistently uses the separator of the platform, even
+// for a search directory written with slashes.
+func formatPath(p, style string, sep byte) string {
+	p = slashPath(p, sep)
+	if style == pathStylePosix || sep == '/' {
+		return p
+	}
+	return strings.ReplaceAll(p, "/", string(sep))
+}
+
+// outputPath formats a walked path for the output.
+func outputPath(p, style string) string {
+	return formatPath(p, style, filepath.Separator)
+}
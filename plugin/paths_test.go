@@ -0,0 +1,110 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_slashPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		sep      byte
+		expected string
+	}{
+		{path: `C:\work\src\main.go`, sep: '\\', expected: "C:/work/src/main.go"},
+		{path: `src\api\v1`, sep: '\\', expected: "src/api/v1"},
+		{path: "src/api/v1", sep: '\\', expected: "src/api/v1"},
+		{path: "/harness/src/main.go", sep: '/', expected: "/harness/src/main.go"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, slashPath(test.path, test.sep), test.path)
+	}
+}
+
+func Test_slashPath_PathLogic(t *testing.T) {
+	// once normalized, Windows paths are handled by the path package
+	// the same way on every platform.
+	p := slashPath(`src\api\v1\types.proto`, '\\')
+	assert.Equal(t, "types.proto", path.Base(p))
+	assert.Equal(t, "src/api/v1", path.Dir(p))
+	assert.Equal(t, []string{"src", "api", "v1", "types.proto"}, splitSegments("ant", p))
+
+	// the filepath package keeps the native separator of the platform.
+	native := filepath.Join("src", "api", "v1", "types.proto")
+	assert.Equal(t, "src/api/v1/types.proto", slashPath(native, filepath.Separator))
+}
+
+func Test_slashPath_Matching(t *testing.T) {
+	patterns, err := compilePatterns("ant", []string{"src/**/*.proto", "**/v1/*"}, MatchOptions{})
+	fatalIf(err)
+
+	p := slashPath(`src\api\v1\types.proto`, '\\')
+	for _, pattern := range patterns {
+		assert.True(t, pattern.Match(p), pattern.text)
+		assert.False(t, pattern.Match(`src\api\v1\types.proto`), pattern.text)
+	}
+
+	prefix := newLiteralPrefix("ant", "src/api/**")
+	assert.True(t, prefix.allows("ant", slashPath(`src\api`, '\\'), false))
+}
+
+func Test_formatPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		style    string
+		sep      byte
+		expected string
+	}{
+		{path: `C:\work\src\main.go`, style: "posix", sep: '\\', expected: "C:/work/src/main.go"},
+		{path: `C:\work\src\main.go`, style: "native", sep: '\\', expected: `C:\work\src\main.go`},
+		{path: `src/app\main.go`, style: "native", sep: '\\', expected: `src\app\main.go`},
+		{path: `src/app\main.go`, style: "posix", sep: '\\', expected: "src/app/main.go"},
+		{path: "src/app/main.go", style: "native", sep: '/', expected: "src/app/main.go"},
+		{path: "src/app/main.go", style: "posix", sep: '/', expected: "src/app/main.go"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, formatPath(test.path, test.style, test.sep), "%s %s", test.style, test.path)
+	}
+}
+
+func Test_Exec_PathStylePosix(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:    "abc/def/one.*",
+		PathStyle: "posix",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	root := slashPath(tempDir, filepath.Separator)
+	assert.Contains(t, paths, root+"/abc/def/one.txt")
+	assert.Contains(t, paths, root+"/abc/def/one.yml")
+	assert.Contains(t, paths, root+"/abc/def/one.xml")
+}
+
+func Test_validateArg_UnknownPathStyle(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	err := validateArgs(Args{
+		Filter:    "**/*.txt",
+		PathStyle: "windows",
+	})
+	assert.EqualError(t, err, `unknown path style "windows", expected native or posix`)
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadPatterns compiles the include and exclude patterns configured
+// by PLUGIN_GLOB, PLUGIN_EXCLUDES and the rules of PLUGIN_PATTERNS_FILE.
+func loadPatterns(args Args) (includes, excludes []pattern, err error) {
+	if includes, err = compileFor(args, splitPatterns(args.Filter)); err != nil {
+		return nil, nil, err
+	}
+	if excludes, err = compileFor(args, splitPatterns(args.Excludes)); err != nil {
+		return nil, nil, err
+	}
+	if args.PatternsFile == "" {
+		return includes, excludes, nil
+	}
+
+	f, err := os.Open(args.PatternsFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open patterns file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text, exclude, ok := parsePatternLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		if exclude && groupLine(args, scanner.Text()) {
+			return nil, nil, fmt.Errorf(`%s:%d: %q starts with a !( pattern group, write \!( to include the group or !\( to exclude a pattern starting with (`, args.PatternsFile, line, strings.TrimSpace(scanner.Text()))
+		}
+		compiled, err := compileFor(args, []string{text})
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %w", args.PatternsFile, line, err)
+		}
+		if exclude {
+			excludes = append(excludes, compiled...)
+		} else {
+			includes = append(includes, compiled...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read patterns file %s: %w", args.PatternsFile, err)
+	}
+	return includes, excludes, nil
+}
+
+// groupLine reports whether a line of a patterns file starts with an
+// Ant pattern group such as !(*.md), which could either be the group
+// or an exclude of a pattern starting with a parenthesis.
+func groupLine(args Args, line string) bool {
+	mode := args.MatchMode
+	if mode == "" {
+		mode = defaultMatchMode
+	}
+	return args.Compiler == nil && mode == "ant" && strings.HasPrefix(strings.TrimSpace(line), "!(")
+}
+
+// parsePatternLine parses a line of a patterns file. A leading !
+// marks an exclude and a leading # starts a comment, both of which
+// can be escaped with a backslash. It returns false for blank lines
+// and comments.
+func parsePatternLine(line string) (text string, exclude, ok bool) {
+	text = strings.TrimSpace(line)
+	switch {
+	case text == "", strings.HasPrefix(text, "#"):
+		return "", false, false
+	case strings.HasPrefix(text, `\#`), strings.HasPrefix(text, `\!`):
+		return text[1:], false, true
+	case strings.HasPrefix(text, "!"):
+		if text = strings.TrimSpace(text[1:]); text == "" {
+			return "", false, false
+		}
+		return text, true, true
+	}
+	return text, false, true
+}
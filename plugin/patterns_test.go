@@ -0,0 +1,124 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parsePatternLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		text    string
+		exclude bool
+		ok      bool
+	}{
+		{line: "**/*.go", text: "**/*.go", ok: true},
+		{line: "  **/*.go  ", text: "**/*.go", ok: true},
+		{line: "!**/*_test.go", text: "**/*_test.go", exclude: true, ok: true},
+		{line: "! **/vendor/**", text: "**/vendor/**", exclude: true, ok: true},
+		{line: `\!important.txt`, text: "!important.txt", ok: true},
+		{line: `\#notes.md`, text: "#notes.md", ok: true},
+		{line: "# go sources", ok: false},
+		{line: "   ", ok: false},
+		{line: "!", ok: false},
+	}
+	for _, test := range tests {
+		text, exclude, ok := parsePatternLine(test.line)
+		assert.Equal(t, test.ok, ok, test.line)
+		assert.Equal(t, test.text, text, test.line)
+		assert.Equal(t, test.exclude, exclude, test.line)
+	}
+}
+
+func Test_loadPatterns_InvalidRule(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "findfiles")
+	fatalIf(err)
+	defer os.RemoveAll(tempDir)
+
+	name := filepath.Join(tempDir, "patterns.txt")
+	fatalIf(os.WriteFile(name, []byte("# sources\n**/*.go\n\n!**/build-[0-9/**\n"), 0644))
+
+	_, _, err = loadPatterns(Args{PatternsFile: name})
+	assert.EqualError(t, err, name+`:4: invalid ant pattern "**/build-[0-9/**": unterminated character class`)
+}
+
+func Test_loadPatterns_LeadingGroup(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "findfiles")
+	fatalIf(err)
+	defer os.RemoveAll(tempDir)
+
+	name := filepath.Join(tempDir, "patterns.txt")
+	fatalIf(os.WriteFile(name, []byte("**/*.go\n!(*.md)\n"), 0644))
+
+	_, _, err = loadPatterns(Args{PatternsFile: name})
+	assert.EqualError(t, err, name+`:2: "!(*.md)" starts with a !( pattern group, write \!( to include the group or !\( to exclude a pattern starting with (`)
+
+	fatalIf(os.WriteFile(name, []byte("\\!(*.md)\n!\\(draft)\n"), 0644))
+	includes, excludes, err := loadPatterns(Args{PatternsFile: name})
+	assert.NoError(t, err)
+	assert.Len(t, includes, 1)
+	assert.True(t, includes[0].Match("main.go"))
+	assert.False(t, includes[0].Match("README.md"))
+	assert.Len(t, excludes, 1)
+	assert.True(t, excludes[0].Match("(draft)"))
+
+	// other match modes have no pattern groups.
+	fatalIf(os.WriteFile(name, []byte("!(draft)\n"), 0644))
+	_, excludes, err = loadPatterns(Args{PatternsFile: name, MatchMode: "regex"})
+	assert.NoError(t, err)
+	assert.Len(t, excludes, 1)
+	assert.Equal(t, "(draft)", excludes[0].text)
+}
+
+func Test_loadPatterns_MissingFile(t *testing.T) {
+	_, _, err := loadPatterns(Args{PatternsFile: "file-not-exist.txt"})
+	assert.Error(t, err)
+}
+
+func Test_validateArg_PatternsFileOnly(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "findfiles")
+	fatalIf(err)
+	defer os.RemoveAll(tempDir)
+
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	name := filepath.Join(tempDir, "patterns.txt")
+	fatalIf(os.WriteFile(name, []byte("**/*.go\n"), 0644))
+	assert.NoError(t, validateArgs(Args{PatternsFile: name}))
+
+	fatalIf(os.WriteFile(name, []byte("!**/*.go\n"), 0644))
+	assert.EqualError(t, validateArgs(Args{PatternsFile: name}), "filter is empty")
+}
+
+func Test_Exec_DirBase_PatternsFile(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	name := filepath.Join(tempDir, "patterns.txt")
+	fatalIf(os.WriteFile(name, []byte("# text files outside def\nabc/**/*.yml\n!abc/def/**\n"), 0644))
+
+	args := Args{
+		Filter:       "?.xyz",
+		Excludes:     "b.*",
+		PatternsFile: name,
+		TargetDir:    tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	patterns := map[string]string{}
+	for _, file := range files {
+		patterns[file.Path] = file.Pattern
+	}
+	assert.Equal(t, "?.xyz", patterns[filepath.Join(tempDir, "a.xyz")])
+	assert.Equal(t, "abc/**/*.yml", patterns[filepath.Join(tempDir, "abc/one.yml")])
+}
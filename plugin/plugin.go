@@ -11,12 +11,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/georgeJobs/go-antpathmatcher"
 	"github.com/sirupsen/logrus"
 )
 
+// Pattern bases select the path the patterns are matched against.
+const (
+	patternBaseDir  = "dir"
+	patternBaseFull = "full"
+)
+
 // Args provides plugin execution arguments.
 type Args struct {
 	Pipeline
@@ -24,26 +31,151 @@ type Args struct {
 	// Level defines the plugin log level.
 	Level string `envconfig:"PLUGIN_LOG_LEVEL"`
 
-	// Ant style pattern Glob pattern to search for files. (required)
+	// Ant style pattern Glob pattern to search for files. Multiple patterns
+	// can be separated by commas or newlines. (required)
 	Filter string `envconfig:"PLUGIN_GLOB"`
 
-	// Glob pattern to exclude files from the search (optional) (default: none)
+	// Glob patterns to exclude files from the search, separated by commas or
+	// newlines. A path matching any of them is dropped. (optional) (default: none)
 	Excludes string `envconfig:"PLUGIN_EXCLUDES"`
 
-	// Directory in which to perform the search. If not specified, the current directory is used. (optional)
+	// File with one include pattern per line, merged with the glob and
+	// excludes patterns. Lines starting with ! are exclude patterns and lines
+	// starting with # are comments. (optional)
+	PatternsFile string `envconfig:"PLUGIN_PATTERNS_FILE"`
+
+	// Ordered filter rules, one per line, where + pattern includes and
+	// - pattern excludes the matching paths. They take precedence over the
+	// glob and excludes patterns. (optional)
+	Rules string `envconfig:"PLUGIN_RULES"`
+
+	// Matching rule deciding whether a path is included, either first or
+	// last. (optional) (default: last)
+	RuleOrder string `envconfig:"PLUGIN_RULE_ORDER" default:"last"`
+
+	// Pattern syntax used by the include and exclude patterns, one of ant,
+	// regex, glob, gitignore or a name passed to RegisterMatcher. (optional) (default: ant)
+	MatchMode string `envconfig:"PLUGIN_MATCH_MODE" default:"ant"`
+
+	// Compiler compiles the include and exclude patterns in place of the
+	// match mode, for library callers bringing their own matcher. (optional)
+	Compiler Compiler `ignored:"true"`
+
+	// Match the include and exclude patterns regardless of case. (optional) (default: false)
+	CaseInsensitive bool `envconfig:"PLUGIN_CASE_INSENSITIVE"`
+
+	// Unicode normalization form the paths and the include and exclude
+	// patterns are converted to before matching, one of none, nfc or nfd.
+	// (optional) (default: none)
+	UnicodeNormalization string `envconfig:"PLUGIN_UNICODE_NORMALIZATION" default:"none"`
+
+	// Apply Ant's default excludes, which drop version control metadata such
+	// as **/.git/** and editor backup files such as **/*~. They apply when
+	// left nil, so that the zero Args keeps them on. (optional) (default: true)
+	DefaultExcludes *bool `envconfig:"PLUGIN_DEFAULT_EXCLUDES"`
+
+	// Ant style patterns added to the default excludes, separated by commas or
+	// newlines. Ignored when the default excludes are disabled. (optional)
+	DefaultExcludesAdd string `envconfig:"PLUGIN_DEFAULT_EXCLUDES_ADD"`
+
+	// Skip paths ignored by the .gitignore files found during the search and
+	// by .git/info/exclude. (optional) (default: false)
+	Gitignore bool `envconfig:"PLUGIN_GITIGNORE"`
+
+	// Path the patterns are matched against, either dir for the path relative
+	// to the search directory or full for the walked path including the
+	// search directory. (optional) (default: dir)
+	PatternBase string `envconfig:"PLUGIN_PATTERN_BASE" default:"dir"`
+
+	// List the files of the Docker build context rooted at the search
+	// directory, applying its .dockerignore file. The glob is optional in
+	// this mode and narrows down the listing. (optional) (default: false)
+	DockerContext bool `envconfig:"PLUGIN_DOCKER_CONTEXT"`
+
+	// Path of the Dockerfile relative to the search directory, used to find
+	// a <Dockerfile>.dockerignore file. (optional) (default: Dockerfile)
+	Dockerfile string `envconfig:"PLUGIN_DOCKERFILE" default:"Dockerfile"`
+
+	// Write a trace of the rules evaluated for every visited path, and the
+	// resulting verdict, to the explain file. (optional) (default: false)
+	Explain bool `envconfig:"PLUGIN_EXPLAIN"`
+
+	// File the explain trace is written to. (optional) (default: findfiles-explain.txt)
+	ExplainFile string `envconfig:"PLUGIN_EXPLAIN_FILE" default:"findfiles-explain.txt"`
+
+	// Separator of the paths written to the output, either native for the
+	// separator of the platform or posix for slashes. (optional) (default: native)
+	PathStyle string `envconfig:"PLUGIN_PATH_STYLE" default:"native"`
+
+	// Descend into the directories symbolic links point to, except for links
+	// leading back to one of their parent directories. (optional) (default: false)
+	FollowSymlinks bool `envconfig:"PLUGIN_FOLLOW_SYMLINKS"`
+
+	// Only list the symbolic links whose target does not exist. (optional) (default: false)
+	BrokenSymlinks bool `envconfig:"PLUGIN_BROKEN_SYMLINKS"`
+
+	// Do not descend into directories held by another file system than the
+	// search directory, such as mounted volumes. (optional) (default: false)
+	OneFileSystem bool `envconfig:"PLUGIN_ONE_FILE_SYSTEM"`
+
+	// Maximum depth of the paths, relative to the search directory, where
+	// 1 only searches the entries of the directory. The search does not
+	// descend any deeper. (optional) (default: 0 for no limit)
+	MaxDepth int `envconfig:"PLUGIN_MAX_DEPTH"`
+
+	// Minimum depth of the paths, relative to the search directory, where
+	// 2 skips the entries of the directory. (optional) (default: 0 for no limit)
+	MinDepth int `envconfig:"PLUGIN_MIN_DEPTH"`
+
+	// Handling of the paths that cannot be read, either fail to abort the
+	// search, skip to ignore them, or report to list them in the
+	// FILES_ERRORS output. (optional) (default: fail)
+	OnError string `envconfig:"PLUGIN_ON_ERROR" default:"fail"`
+
+	// Directories in which to perform the search, separated by commas or
+	// newlines. If not specified, the current directory is used. (optional)
 	TargetDir string `envconfig:"PLUGIN_DIR"`
 }
 
 type FileInfo struct {
 	Name         string `json:"name"`
 	Path         string `json:"path"`
+	Root         string `json:"root"`
 	IsDirectory  bool   `json:"isDirectory"`
 	Length       int64  `json:"length"`
 	LastModified string `json:"lastModified"`
+	Pattern      string `json:"pattern"`
+
+	// RawPath is the path as found on disk, set when the path is
+	// converted to a Unicode normalization form.
+	RawPath string `json:"rawPath,omitempty"`
+
+	// IsSymlink reports whether the path is a symbolic link.
+	IsSymlink bool `json:"isSymlink,omitempty"`
+
+	// LinkTarget is the target of a symbolic link as stored in the
+	// link, which may be relative to the directory of the link.
+	LinkTarget string `json:"linkTarget,omitempty"`
+
+	// ResolvedTarget is the absolute path of the target of a symbolic
+	// link, with every symbolic link resolved when the target exists.
+	ResolvedTarget string `json:"resolvedTarget,omitempty"`
+
+	// Broken reports whether the target of a symbolic link does not
+	// exist.
+	Broken bool `json:"broken,omitempty"`
+
+	// RealPath is the absolute path with every symbolic link resolved,
+	// set when symbolic links are followed.
+	RealPath string `json:"realPath,omitempty"`
 }
 
 // Exec executes the plugin.
 func Exec(ctx context.Context, args Args) error {
+	args, err := expandArgs(args)
+	if err != nil {
+		return err
+	}
 	if err := validateArgs((args)); err != nil {
 		return err
 	}
@@ -51,10 +183,11 @@ func Exec(ctx context.Context, args Args) error {
 	logger := logrus.
 		WithField("glob", args.Filter).
 		WithField("excludes", args.Excludes).
+		WithField("mode", args.MatchMode).
 		WithField("dir", args.TargetDir)
 	logger.Infoln("searching files")
 
-	files, err := applyFilter(logger, args)
+	files, walkErrors, err := findFiles(logger, args)
 	if err != nil {
 		return err
 	}
@@ -67,40 +200,108 @@ func Exec(ctx context.Context, args Args) error {
 	if err = writeEnvToFile("FILES_INFO", string(jsonOutput)); err != nil {
 		return err
 	}
-	return nil
-}
 
-func applyFilter(logger *logrus.Entry, args Args) ([]FileInfo, error) {
-	var files []FileInfo
-	m := antpathmatcher.NewAntPathMatcher()
+	if args.OnError == onErrorReport {
+		logger.Infof("%d paths could not be read", len(walkErrors))
 
-	if args.TargetDir == "" {
-		args.TargetDir = "."
+		jsonErrors, err := json.Marshal(walkErrors)
+		if err != nil {
+			return logError(logger, fmt.Sprintf("Error marshalling JSON: %v", err), err)
+		}
+		if err = writeEnvToFile("FILES_ERRORS", string(jsonErrors)); err != nil {
+			return err
+		}
 	}
 
-	err := filepath.WalkDir(args.TargetDir, func(path string, d os.DirEntry, e error) error {
+	if args.DockerContext {
+		var size int64
+		for _, file := range files {
+			size += file.Length
+		}
+		logger.Infof("docker build context has %d files, %d bytes", len(files), size)
+
+		// the size has its own output variable, so that FILES_INFO
+		// stays a list of files whatever the mode.
+		if err = writeEnvToFile("CONTEXT_SIZE", strconv.FormatInt(size, 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		if m.Match(args.Filter, path) {
-			if m.Match(args.Excludes, path) {
-				logger.Debugf("path %s match exclude criteria %s", path, args.Excludes)
+func applyFilter(logger *logrus.Entry, args Args) ([]FileInfo, error) {
+	files, _, err := findFiles(logger, args)
+	return files, err
+}
 
-			} else {
-				file, err := getFileInfo(path)
-				if err != nil {
-					return logError(logger, fmt.Sprintf("error to get file info of path %s", path), err)
-				}
+// matchAny returns the first pattern matching the path.
+func matchAny(patterns []pattern, path string) (string, bool) {
+	for _, p := range patterns {
+		if p.Match(path) {
+			return p.text, true
+		}
+	}
+	return "", false
+}
 
-				files = append(files, file)
+// splitPatterns splits a comma or newline separated list of patterns,
+// dropping blank entries and duplicates. Commas inside braces belong
+// to brace alternatives, and commas escaped with a backslash belong to
+// the pattern, so neither separates patterns.
+func splitPatterns(s string) []string {
+	var patterns []string
+	seen := map[string]bool{}
+	depth := 0
+	escaped := false
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool {
+		if escaped {
+			escaped = false
+			if r == ',' {
+				return false
+			}
+		} else if r == '\\' {
+			escaped = true
+		}
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
 			}
+		case ',':
+			return depth == 0
+		case '\n', '\r':
+			depth = 0
+			return true
+		}
+		return false
+	}) {
+		pattern := strings.TrimSpace(field)
+		if pattern == "" || seen[pattern] {
+			continue
 		}
+		seen[pattern] = true
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
 
-		return nil
-	})
-	if err != nil {
-		return []FileInfo{}, err
+// matchOptionsFor returns the options used to compile the include and
+// exclude patterns.
+func matchOptionsFor(args Args) MatchOptions {
+	return MatchOptions{
+		CaseInsensitive: args.CaseInsensitive,
 	}
+}
 
-	return files, nil
+// relPath returns the slash separated path relative to the search root.
+func relPath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return slashPath(path, filepath.Separator)
+	}
+	return slashPath(rel, filepath.Separator)
 }
 
 func getFileInfo(path string) (FileInfo, error) {
@@ -111,13 +312,21 @@ func getFileInfo(path string) (FileInfo, error) {
 	if err != nil {
 		return FileInfo{}, err
 	}
+	file := newFileInfo(path, fi)
+	if fi.Mode()&os.ModeSymlink != 0 {
+		describeLink(&file, path)
+	}
+	return file, nil
+}
+
+func newFileInfo(path string, fi os.FileInfo) FileInfo {
 	return FileInfo{
 		Name:         fi.Name(),
 		Path:         path,
 		IsDirectory:  fi.IsDir(),
 		Length:       fi.Size(),
 		LastModified: fi.ModTime().Format(time.RFC3339),
-	}, nil
+	}
 }
 
 func logError(logger *logrus.Entry, message string, err error) error {
@@ -126,9 +335,58 @@ func logError(logger *logrus.Entry, message string, err error) error {
 }
 
 func validateArgs(args Args) error {
-	if args.Filter == "" {
+	includes, excludes, err := loadPatterns(args)
+	if err != nil {
+		return err
+	}
+	rules, err := parseRules(args)
+	if err != nil {
+		return err
+	}
+	if len(includes) == 0 && !hasInclude(rules) && !args.DockerContext {
 		return errors.New("filter is empty")
 	}
+	if roots := searchRoots(args.TargetDir); args.DockerContext && len(roots) > 1 {
+		return fmt.Errorf("a docker build context has a single directory, got %d", len(roots))
+	}
+	switch args.RuleOrder {
+	case "", ruleOrderFirst, ruleOrderLast:
+	default:
+		return fmt.Errorf("unknown rule order %q, expected %s or %s", args.RuleOrder, ruleOrderFirst, ruleOrderLast)
+	}
+	switch args.UnicodeNormalization {
+	case "", normalizationNone, normalizationNFC, normalizationNFD:
+	default:
+		return fmt.Errorf("unknown unicode normalization %q, expected one of %s, %s or %s", args.UnicodeNormalization, normalizationNone, normalizationNFC, normalizationNFD)
+	}
+	if args.MaxDepth < 0 || args.MinDepth < 0 {
+		return errors.New("depth limits must not be negative")
+	}
+	if args.MaxDepth > 0 && args.MinDepth > args.MaxDepth {
+		return fmt.Errorf("minimum depth %d is greater than the maximum depth %d", args.MinDepth, args.MaxDepth)
+	}
+	switch args.OnError {
+	case "", onErrorFail, onErrorSkip, onErrorReport:
+	default:
+		return fmt.Errorf("unknown error mode %q, expected one of %s, %s or %s", args.OnError, onErrorFail, onErrorSkip, onErrorReport)
+	}
+	switch args.PathStyle {
+	case "", pathStyleNative, pathStylePosix:
+	default:
+		return fmt.Errorf("unknown path style %q, expected %s or %s", args.PathStyle, pathStyleNative, pathStylePosix)
+	}
+	switch args.PatternBase {
+	case "", patternBaseDir, patternBaseFull:
+	default:
+		return fmt.Errorf("unknown pattern base %q, expected %s or %s", args.PatternBase, patternBaseDir, patternBaseFull)
+	}
+	warnings, err := lintPatterns(args, includes, excludes, rules)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		logrus.Warnln(warning)
+	}
 	if os.Getenv("DRONE_OUTPUT") == "" {
 		return errors.New("missing DRONE_OUTPUT environment variable")
 	}
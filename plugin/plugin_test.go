@@ -149,9 +149,10 @@ func Test_Exec_Absolute_NoExcludes(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	args := Args{
-		Filter:    "/**/*.txt",
-		Excludes:  "",
-		TargetDir: tempDir,
+		Filter:      "/**/*.txt",
+		Excludes:    "",
+		TargetDir:   tempDir,
+		PatternBase: "full",
 	}
 
 	files, err := applyFilter(NoopLogger(), args)
@@ -173,9 +174,10 @@ func Test_Exec_Absolute_ExcludeDir(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	args := Args{
-		Filter:    "/**/*.txt",
-		Excludes:  "/**/def/*",
-		TargetDir: tempDir,
+		Filter:      "/**/*.txt",
+		Excludes:    "/**/def/*",
+		TargetDir:   tempDir,
+		PatternBase: "full",
 	}
 
 	files, err := applyFilter(NoopLogger(), args)
@@ -195,9 +197,10 @@ func Test_Exec_Absolute_ExcludeFileExtension(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	args := Args{
-		Filter:    "/**/def/*",
-		Excludes:  "/**/*.txt",
-		TargetDir: tempDir,
+		Filter:      "/**/def/*",
+		Excludes:    "/**/*.txt",
+		TargetDir:   tempDir,
+		PatternBase: "full",
 	}
 
 	files, err := applyFilter(NoopLogger(), args)
@@ -217,8 +220,9 @@ func Test_Exec_AbsoluteSingleCharacter(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	args := Args{
-		Filter:    "/**/?.xyz",
-		TargetDir: tempDir,
+		Filter:      "/**/?.xyz",
+		TargetDir:   tempDir,
+		PatternBase: "full",
 	}
 
 	files, err := applyFilter(NoopLogger(), args)
@@ -238,8 +242,9 @@ func Test_Exec_AbsoluteDirectoryWildcards(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	args := Args{
-		Filter:    "/**/harness/**",
-		TargetDir: tempDir,
+		Filter:      "/**/harness/**",
+		TargetDir:   tempDir,
+		PatternBase: "full",
 	}
 
 	files, err := applyFilter(NoopLogger(), args)
@@ -256,6 +261,105 @@ func Test_Exec_AbsoluteDirectoryWildcards(t *testing.T) {
 	assert.Contains(t, paths, filepath.Join(tempDir, "abc/test/harness/community/go.sum"))
 }
 
+// --
+// PATTERNS RELATIVE TO THE SEARCH DIRECTORY
+
+func Test_Exec_DirBase_RootFiles(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:    "*.xyz",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "a.xyz"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "b.xyz"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "a1.xyz"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "b1.xyz"))
+}
+
+func Test_Exec_DirBase_TopLevelEntries(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	for _, args := range []Args{
+		{Filter: "*", TargetDir: tempDir},
+		{Filter: "**", TargetDir: tempDir, MaxDepth: 1},
+	} {
+		files, err := applyFilter(NoopLogger(), args)
+		assert.NoError(t, err)
+
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, file.Path)
+		}
+		assert.ElementsMatch(t, []string{
+			filepath.Join(tempDir, "a.xyz"),
+			filepath.Join(tempDir, "a1.xyz"),
+			filepath.Join(tempDir, "abc"),
+			filepath.Join(tempDir, "b.xyz"),
+			filepath.Join(tempDir, "b1.xyz"),
+		}, paths, args.Filter)
+	}
+}
+
+func Test_Exec_DirBase_ExcludeDir(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:      "abc/**/*.txt",
+		Excludes:    "abc/def/**",
+		TargetDir:   tempDir,
+		PatternBase: "dir",
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/two.txt"))
+}
+
+func Test_Exec_FullBase_RelativePattern(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:      "*.xyz",
+		TargetDir:   tempDir,
+		PatternBase: "full",
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func Test_validateArg_UnknownPatternBase(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	err := validateArgs(Args{
+		Filter:      "**/*.txt",
+		PatternBase: "root",
+	})
+	assert.EqualError(t, err, `unknown pattern base "root", expected dir or full`)
+}
+
 // --
 // RELATIVE PATTERN & PATH
 
@@ -366,6 +470,70 @@ func Test_Exec_Relative_NoExcludes(t *testing.T) {
 	assert.Contains(t, paths, "abc/two.txt")
 }
 
+// --
+// MULTIPLE PATTERNS
+
+func Test_splitPatterns(t *testing.T) {
+	assert.Equal(t, []string{"**/*.go", "**/go.mod", "**/*.txt"},
+		splitPatterns("**/*.go, **/go.mod\n**/*.txt\n\n**/*.go"))
+	assert.Empty(t, splitPatterns(" , \n"))
+}
+
+func Test_splitPatterns_EscapedComma(t *testing.T) {
+	assert.Equal(t, []string{`**/a\,b.txt`, "**/*.go"}, splitPatterns(`**/a\,b.txt,**/*.go`))
+	assert.Equal(t, []string{`**/a\\`, "b.txt"}, splitPatterns(`**/a\\,b.txt`))
+}
+
+func Test_validateArg_BlankFilterList(t *testing.T) {
+	err := validateArgs(Args{
+		Filter: " , ",
+	})
+	assert.EqualError(t, err, "filter is empty")
+}
+
+func Test_Exec_Relative_MultipleGlobs(t *testing.T) {
+	setupRelativeFilesAndFolders()
+	defer cleanupRelativeFilesAndFolders()
+
+	args := Args{
+		Filter: "abc/**/go.mod,abc/**/*.go\nabc/**/community/*\nabc/**/community",
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	patterns := map[string]string{}
+	for _, file := range files {
+		patterns[file.Path] = file.Pattern
+	}
+	assert.Equal(t, "abc/**/go.mod", patterns["abc/test/harness/community/go.mod"])
+	assert.Equal(t, "abc/**/*.go", patterns["abc/test/harness/community/main.go"])
+	assert.Equal(t, "abc/**/community/*", patterns["abc/test/harness/community/go.sum"])
+	assert.Equal(t, "abc/**/community", patterns["abc/test/harness/community"])
+}
+
+func Test_Exec_Relative_MultipleExcludes(t *testing.T) {
+	setupRelativeFilesAndFolders()
+	defer cleanupRelativeFilesAndFolders()
+
+	args := Args{
+		Filter:   "abc/**",
+		Excludes: "abc/def/**\nabc/test/**, **/*.txt",
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, "abc")
+	assert.Contains(t, paths, "abc/one.yml")
+}
+
 func NoopLogger() *logrus.Entry {
 	log := logrus.New()
 	log.SetOutput(io.Discard)
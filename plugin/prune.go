@@ -0,0 +1,101 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"strings"
+)
+
+// literalPrefix describes the leading path segments of a pattern that
+// are free of wildcards, which tell the directories a match can be
+// found in.
+type literalPrefix struct {
+	absolute bool
+	segments []string
+
+	// literal reports whether the whole pattern is made of literal
+	// segments, in which case nothing below it can match.
+	literal bool
+
+	// tree reports whether a directory matching the pattern has its
+	// whole contents matching it, as with a trailing /** segment.
+	tree bool
+}
+
+// newLiteralPrefix computes the literal prefix of a pattern. Ant
+// patterns ignore empty segments, while glob patterns match the
+// separators literally.
+func newLiteralPrefix(mode, text string) literalPrefix {
+	p := literalPrefix{
+		absolute: strings.HasPrefix(text, "/"),
+		literal:  true,
+		tree:     mode == "ant" && (text == "**" || strings.HasSuffix(text, "/**")),
+	}
+	for _, segment := range splitSegments(mode, text) {
+		// Ant segments may also hold pattern groups such as @(a|b).
+		if strings.ContainsAny(segment, `*?[{\`) || (mode == "ant" && strings.Contains(segment, "(")) {
+			p.literal = false
+			break
+		}
+		p.segments = append(p.segments, segment)
+	}
+	return p
+}
+
+// allows reports whether the pattern can match a path below the
+// directory.
+func (p literalPrefix) allows(mode, dir string, fold bool) bool {
+	if mode == "ant" && strings.HasPrefix(dir, "/") != p.absolute {
+		return false
+	}
+	segments := splitSegments(mode, dir)
+	for i, segment := range segments {
+		if i == len(p.segments) {
+			return !p.literal
+		}
+		if segment != p.segments[i] && !(fold && strings.EqualFold(segment, p.segments[i])) {
+			return false
+		}
+	}
+	return !p.literal || len(segments) < len(p.segments)
+}
+
+// splitSegments splits a pattern or path into the segments matched
+// by the match mode.
+func splitSegments(mode, s string) []string {
+	if mode != "ant" {
+		return strings.Split(s, "/")
+	}
+	return strings.FieldsFunc(s, func(r rune) bool { return r == '/' })
+}
+
+// prunable reports whether the walk can skip the contents of a
+// directory, given by the path the rules are matched against. The
+// rules are considered by decreasing priority: the contents can be
+// skipped once an exclude rule matches all of them, as long as no
+// include rule of higher priority can match below the directory.
+func (s *search) prunable(dir string) (string, bool) {
+	if s.prefixes == nil {
+		return "", false
+	}
+	for i, r := range s.rules {
+		prefix := s.prefixes[i]
+		if r.include {
+			if prefix.allows(s.mode, dir, s.args.CaseInsensitive) {
+				return "", false
+			}
+			continue
+		}
+		if prefix.tree && r.Match(dir) {
+			return "exclude pattern " + r.text + " matches everything below it", true
+		}
+	}
+	// the build context includes every file unless an include
+	// rule narrows it down.
+	if s.docker != nil && !hasInclude(s.rules) {
+		return "", false
+	}
+	return "no include pattern can match below it", true
+}
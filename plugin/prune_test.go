@@ -0,0 +1,219 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_literalPrefix_Allows(t *testing.T) {
+	tests := []struct {
+		mode    string
+		pattern string
+		allow   []string
+		deny    []string
+	}{
+		{
+			mode:    "ant",
+			pattern: "src/api/**/*.proto",
+			allow:   []string{"src", "src/api", "src/api/v1/types"},
+			deny:    []string{"docs", "src/web", "srcs/api"},
+		},
+		{
+			mode:    "ant",
+			pattern: "**/*.go",
+			allow:   []string{"abc", "abc/def"},
+		},
+		{
+			mode:    "ant",
+			pattern: "abc/def/one.txt",
+			allow:   []string{"abc", "abc/def"},
+			deny:    []string{"abc/def/one.txt", "abc/ghi"},
+		},
+		{
+			mode:    "ant",
+			pattern: "/tmp/abc/**",
+			allow:   []string{"/tmp", "/tmp/abc/def"},
+			deny:    []string{"tmp", "/var"},
+		},
+		{
+			mode:    "ant",
+			pattern: "abc/{def,ghi}/*.txt",
+			allow:   []string{"abc", "abc/xyz"},
+			deny:    []string{"xyz"},
+		},
+		{
+			mode:    "glob",
+			pattern: "abc/*/one.txt",
+			allow:   []string{"abc", "abc/def"},
+			deny:    []string{"def"},
+		},
+	}
+	for _, test := range tests {
+		prefix := newLiteralPrefix(test.mode, test.pattern)
+		for _, dir := range test.allow {
+			assert.True(t, prefix.allows(test.mode, dir, false), "%s pattern %q below %q", test.mode, test.pattern, dir)
+		}
+		for _, dir := range test.deny {
+			assert.False(t, prefix.allows(test.mode, dir, false), "%s pattern %q below %q", test.mode, test.pattern, dir)
+		}
+	}
+}
+
+func Test_literalPrefix_CaseInsensitive(t *testing.T) {
+	prefix := newLiteralPrefix("ant", "SRC/**/*.go")
+	assert.True(t, prefix.allows("ant", "src/api", true))
+	assert.False(t, prefix.allows("ant", "src/api", false))
+}
+
+func Test_Exec_PruneIncludePrefix(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	explainFile := filepath.Join(tempDir, "explain.txt")
+	args := Args{
+		Filter:          "abc/def/*.txt",
+		DefaultExcludes: boolPtr(false),
+		TargetDir:       tempDir,
+		Explain:         true,
+		ExplainFile:     explainFile,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+
+	assert.Contains(t, string(content), filepath.Join(tempDir, "abc/test")+`
+  include abc/def/*.txt: no match
+  verdict: excluded, no include pattern matched, no include pattern can match below it, directory not descended into
+`)
+	assert.NotContains(t, string(content), filepath.Join(tempDir, "abc/test/harness"))
+}
+
+func Test_Exec_PruneExcludedTree(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	explainFile := filepath.Join(tempDir, "explain.txt")
+	args := Args{
+		Filter:          "**",
+		Excludes:        "**/test/**",
+		DefaultExcludes: boolPtr(false),
+		TargetDir:       tempDir,
+		Explain:         true,
+		ExplainFile:     explainFile,
+	}
+
+	_, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+
+	assert.Contains(t, string(content), filepath.Join(tempDir, "abc/test/harness")+`
+  exclude **/test/**: match
+  include **: match
+  verdict: excluded, exclude pattern **/test/** matched, exclude pattern **/test/** matches everything below it, directory not descended into
+`)
+	assert.NotContains(t, string(content), filepath.Join(tempDir, "abc/test/harness/community"))
+}
+
+func Test_Exec_PruneKeepsReincludedTree(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:    "**/*.go",
+		Excludes:  "**/test/**",
+		Rules:     "+ abc/test/harness/community/main.go",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, filepath.Join(tempDir, "abc/test/harness/community/main.go"), files[0].Path)
+}
+
+func Test_Exec_PruneIncludedDirectory(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:    "abc/def",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, filepath.Join(tempDir, "abc/def"), files[0].Path)
+	assert.True(t, files[0].IsDirectory)
+}
+
+// setupMonorepo creates a tree of services, each with api sources,
+// web sources and installed node modules, which hold most of the
+// files as they do in a real workspace.
+func setupMonorepo(b *testing.B) string {
+	tempDir := b.TempDir()
+	for i := 0; i < 20; i++ {
+		dirs := []string{
+			fmt.Sprintf("src/api/service%d/v1", i),
+			fmt.Sprintf("src/web/app%d/components", i),
+		}
+		for j := 0; j < 10; j++ {
+			dirs = append(dirs, fmt.Sprintf("src/web/app%d/node_modules/lib%d/dist", i, j))
+		}
+		for _, dir := range dirs {
+			fatalIf(os.MkdirAll(filepath.Join(tempDir, dir), 0755))
+			for j := 0; j < 10; j++ {
+				fatalIf(os.WriteFile(filepath.Join(tempDir, dir, fmt.Sprintf("file%d.proto", j)), []byte{}, 0644))
+				fatalIf(os.WriteFile(filepath.Join(tempDir, dir, fmt.Sprintf("file%d.js", j)), []byte{}, 0644))
+			}
+		}
+	}
+	return tempDir
+}
+
+// benchmarkSearch walks the monorepo with the search directories
+// pruned or not, so that the same patterns are matched either way.
+func benchmarkSearch(b *testing.B, args Args, prune bool, expected int) {
+	args.TargetDir = setupMonorepo(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := newWalker(args)
+		if !prune {
+			w.search.prefixes = nil
+		}
+		fatalIf(filepath.WalkDir(args.TargetDir, w.visit))
+		if len(w.files) != expected {
+			b.Fatalf("expected %d files, got %d", expected, len(w.files))
+		}
+	}
+}
+
+func BenchmarkSearch_IncludePrefix(b *testing.B) {
+	benchmarkSearch(b, Args{Filter: "src/api/**/*.proto"}, true, 200)
+}
+
+func BenchmarkSearch_IncludePrefixUnpruned(b *testing.B) {
+	benchmarkSearch(b, Args{Filter: "src/api/**/*.proto"}, false, 200)
+}
+
+func BenchmarkSearch_ExcludeTree(b *testing.B) {
+	benchmarkSearch(b, Args{Filter: "**/*.js", Excludes: "**/node_modules/**"}, true, 400)
+}
+
+func BenchmarkSearch_ExcludeTreeUnpruned(b *testing.B) {
+	benchmarkSearch(b, Args{Filter: "**/*.js", Excludes: "**/node_modules/**"}, false, 400)
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule orders select which matching rule of PLUGIN_RULES decides
+// whether a path is included.
+const (
+	ruleOrderFirst = "first"
+	ruleOrderLast  = "last"
+)
+
+// rule is a compiled include or exclude rule.
+type rule struct {
+	include bool
+	pattern
+}
+
+// parseRules compiles the ordered rules of PLUGIN_RULES, one per
+// line, where + introduces an include rule and - an exclude rule.
+// Blank lines and lines starting with # are skipped.
+func parseRules(args Args) ([]rule, error) {
+	var rules []rule
+	for i, line := range strings.Split(args.Rules, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var include bool
+		switch line[0] {
+		case '+':
+			include = true
+		case '-':
+		default:
+			return nil, fmt.Errorf("rule %d: %q must start with + or -", i+1, line)
+		}
+		text := strings.TrimSpace(line[1:])
+		if text == "" {
+			return nil, fmt.Errorf("rule %d: %q has no pattern", i+1, line)
+		}
+
+		compiled, err := compileFor(args, []string{text})
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		rules = append(rules, rule{include: include, pattern: compiled[0]})
+	}
+	return rules, nil
+}
+
+// orderRules returns every rule of the search by decreasing priority,
+// so that the first matching rule decides. PLUGIN_RULES come first,
+// as written for first match semantics and reversed for last match
+// semantics, followed by the exclude patterns and finally the include
+// patterns, which keeps excludes winning over includes.
+func orderRules(args Args, includes, excludes []pattern, rules []rule) []rule {
+	var ordered []rule
+	if args.RuleOrder == ruleOrderFirst {
+		ordered = append(ordered, rules...)
+	} else {
+		for i := len(rules) - 1; i >= 0; i-- {
+			ordered = append(ordered, rules[i])
+		}
+	}
+	for _, p := range excludes {
+		ordered = append(ordered, rule{pattern: p})
+	}
+	for _, p := range includes {
+		ordered = append(ordered, rule{include: true, pattern: p})
+	}
+	return ordered
+}
+
+// hasInclude reports whether any rule includes paths.
+func hasInclude(rules []rule) bool {
+	for _, r := range rules {
+		if r.include {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRules(t *testing.T) {
+	rules, err := parseRules(Args{Rules: `
+# test data
+- **/testdata/**
++**/testdata/golden/*.json
++   **/*.json
+`})
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+
+	assert.False(t, rules[0].include)
+	assert.Equal(t, "**/testdata/**", rules[0].text)
+	assert.True(t, rules[1].include)
+	assert.Equal(t, "**/testdata/golden/*.json", rules[1].text)
+	assert.True(t, rules[2].include)
+	assert.Equal(t, "**/*.json", rules[2].text)
+}
+
+func Test_parseRules_Invalid(t *testing.T) {
+	tests := []struct {
+		rules string
+		err   string
+	}{
+		{
+			rules: "+ **/*.json\n**/*.yml",
+			err:   `rule 2: "**/*.yml" must start with + or -`,
+		},
+		{
+			rules: "-",
+			err:   `rule 1: "-" has no pattern`,
+		},
+		{
+			rules: "+ **/[a-",
+			err:   `rule 1: invalid ant pattern "**/[a-": unterminated character class`,
+		},
+	}
+	for _, test := range tests {
+		_, err := parseRules(Args{Rules: test.rules, MatchMode: "ant"})
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func Test_orderRules(t *testing.T) {
+	args := Args{
+		Filter:   "**/*.txt",
+		Excludes: "**/def/**",
+		Rules:    "+ **/def/one.*\n- **/*.xml",
+	}
+	includes, excludes, err := loadPatterns(args)
+	fatalIf(err)
+	rules, err := parseRules(args)
+	fatalIf(err)
+
+	var texts []string
+	for _, r := range orderRules(args, includes, excludes, rules) {
+		texts = append(texts, r.text)
+	}
+	assert.Equal(t, []string{"**/*.xml", "**/def/one.*", "**/def/**", "**/*.txt"}, texts)
+
+	args.RuleOrder = "first"
+	texts = nil
+	for _, r := range orderRules(args, includes, excludes, rules) {
+		texts = append(texts, r.text)
+	}
+	assert.Equal(t, []string{"**/def/one.*", "**/*.xml", "**/def/**", "**/*.txt"}, texts)
+}
+
+func Test_Exec_Rules_LastMatch(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Rules:     "+ **/*.{txt,xml}\n- abc/def/**\n+ abc/def/*.xml",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/two.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/one.xml"))
+}
+
+func Test_Exec_Rules_FirstMatch(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Rules:     "+ abc/def/one.*\n- abc/def/**\n+ **/*.{txt,xml}",
+		RuleOrder: "first",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 5)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/two.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/one.xml"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/one.yml"))
+}
+
+func Test_Exec_Rules_ReincludeExcluded(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:    "abc/**/*.txt",
+		Excludes:  "abc/def/**",
+		Rules:     "+ abc/def/two.txt",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+		if file.Path == filepath.Join(tempDir, "abc/def/two.txt") {
+			assert.Equal(t, "abc/def/two.txt", file.Pattern)
+		}
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/two.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/two.txt"))
+}
+
+func Test_validateArg_RulesOnly(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	assert.NoError(t, validateArgs(Args{Rules: "+ **/*.txt"}))
+	assert.EqualError(t, validateArgs(Args{Rules: "- **/*.txt"}), "filter is empty")
+}
+
+func Test_validateArg_UnknownRuleOrder(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	err := validateArgs(Args{
+		Rules:     "+ **/*.txt",
+		RuleOrder: "middle",
+	})
+	assert.EqualError(t, err, `unknown rule order "middle", expected first or last`)
+}
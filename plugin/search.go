@@ -0,0 +1,374 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// search holds the compiled filters of a search and decides, for
+// every walked path, whether it is part of the result.
+type search struct {
+	args   Args
+	logger *logrus.Entry
+
+	rules    []rule
+	defaults []pattern
+
+	// mode is the match mode of the rules, and prefixes holds the
+	// literal prefix of each rule when the mode allows pruning the
+	// walk.
+	mode     string
+	prefixes []literalPrefix
+
+	// normalize converts paths to the Unicode normalization form of
+	// the patterns, nil when paths are matched as they are.
+	normalize func(string) string
+
+	// device is the ID of the device holding the search directory,
+	// set when the search stays on one file system.
+	device *uint64
+
+	ignore *gitignore
+	docker *dockerignore
+	trace  *trace
+}
+
+// decision is the outcome of evaluating the filters for a path.
+type decision struct {
+	// include reports whether the path is part of the result.
+	include bool
+
+	// pattern is the include rule that matched the path.
+	pattern string
+
+	// skipDir reports whether the walk must not descend into
+	// the directory.
+	skipDir bool
+
+	// reason explains the decision.
+	reason string
+}
+
+// newSearch compiles the filters configured by the arguments.
+func newSearch(logger *logrus.Entry, args Args) (*search, error) {
+	s := &search{args: args, logger: logger}
+
+	includes, excludes, err := loadPatterns(args)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := parseRules(args)
+	if err != nil {
+		return nil, err
+	}
+	s.rules = orderRules(args, includes, excludes, rules)
+
+	s.normalize = normalizer(args.UnicodeNormalization)
+
+	s.mode = args.MatchMode
+	if s.mode == "" {
+		s.mode = defaultMatchMode
+	}
+	// only the syntax of Ant and glob patterns tells where they
+	// can match.
+	if args.Compiler == nil && (s.mode == "ant" || s.mode == "glob") {
+		for _, r := range s.rules {
+			s.prefixes = append(s.prefixes, newLiteralPrefix(s.mode, s.normalized(r.text)))
+		}
+	}
+
+	if s.defaults, err = defaultExcludes(args); err != nil {
+		return nil, err
+	}
+	if err = s.setRoot(searchRoots(args.TargetDir)[0]); err != nil {
+		return nil, err
+	}
+	if args.Explain {
+		if s.trace, err = newTrace(logger, args.ExplainFile); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// setRoot prepares the search of a search directory, loading its
+// ignore files.
+func (s *search) setRoot(root string) error {
+	s.args.TargetDir = root
+	s.device = nil
+	if s.args.OneFileSystem {
+		s.device = rootDevice(s.logger, root)
+	}
+
+	var err error
+	if s.args.Gitignore {
+		if s.ignore, err = newGitignore(root); err != nil {
+			return err
+		}
+	}
+	if s.args.DockerContext {
+		if s.docker, err = newDockerignore(root, s.args.Dockerfile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close releases the resources held by the search.
+func (s *search) close() error {
+	if s.trace == nil {
+		return nil
+	}
+	s.logger.Infof("decision trace written to %s", s.trace.name)
+	return s.trace.close()
+}
+
+// decide evaluates the filters for a walked path and records the
+// decision in the trace.
+func (s *search) decide(path string, d fs.DirEntry) (decision, error) {
+	if s.trace.traces(path) {
+		s.logger.Debugf("path %s is the decision trace file", path)
+		return decision{reason: "decision trace file"}, nil
+	}
+	dec, err := s.evaluate(path, d)
+	if err != nil {
+		return decision{}, err
+	}
+	if max := s.args.MaxDepth; !dec.skipDir && d != nil && d.IsDir() && max > 0 {
+		if depth := pathDepth(relPath(s.args.TargetDir, path)); depth >= max {
+			dec.skipDir = true
+			dec.reason += fmt.Sprintf(", maximum depth %d reached", max)
+		}
+	}
+	if !dec.skipDir && d != nil && d.IsDir() && s.mountPoint(d) {
+		s.logger.Debugf("path %s is a mount point, not descended into", path)
+		dec.skipDir = true
+		dec.reason += ", on another file system"
+	}
+	if !dec.skipDir && d != nil && d.IsDir() && path != s.args.TargetDir {
+		if reason, ok := s.prunable(s.target(path)); ok {
+			s.logger.Debugf("path %s is pruned, %s", path, reason)
+			dec.skipDir = true
+			dec.reason += ", " + reason
+		}
+	}
+	if dec.skipDir {
+		dec.reason += ", directory not descended into"
+	}
+	s.trace.verdict(path, dec)
+	return dec, nil
+}
+
+func (s *search) evaluate(path string, d fs.DirEntry) (decision, error) {
+	rel := relPath(s.args.TargetDir, path)
+	isDir := d != nil && d.IsDir()
+
+	if s.ignore != nil && d != nil {
+		var ignored bool
+		var rule *ignoreRule
+		if rel != "." {
+			ignored, rule = s.ignore.ignored(rel, isDir)
+		}
+		if rule != nil {
+			s.trace.check("gitignore", ruleSource(rule.file, rule.line, rule.text), true)
+		} else {
+			s.trace.check("gitignore", "", false)
+		}
+		if ignored {
+			s.logger.Debugf("path %s is ignored by git", path)
+			return decision{skipDir: isDir, reason: "ignored by git"}, nil
+		}
+		if isDir {
+			if err := s.ignore.load(s.args.TargetDir, rel); err != nil {
+				return decision{}, err
+			}
+		}
+	}
+
+	if s.docker != nil && d != nil && rel != "." {
+		excluded, rule := s.docker.excluded(rel)
+		if rule != nil {
+			s.trace.check("dockerignore", ruleSource(rule.file, rule.line, rule.text), true)
+		} else {
+			s.trace.check("dockerignore", "", false)
+		}
+		if excluded {
+			s.logger.Debugf("path %s is excluded by %s", path, s.docker.file)
+			// exceptions may re-include paths below an excluded
+			// directory, so it can only be skipped without them.
+			return decision{skipDir: isDir && !s.docker.exceptions, reason: "ignored by " + s.docker.file}, nil
+		}
+	}
+
+	if rel != "." {
+		if exclude, ok := s.match("default exclude", s.defaults, rel); ok {
+			s.logger.Debugf("path %s match default exclude %s", path, exclude)
+			skipDir := isDir && s.excludesTree(rel)
+			return decision{skipDir: skipDir, reason: "default exclude " + exclude + " matched"}, nil
+		}
+	}
+
+	// patterns relative to the search directory describe the paths
+	// below it, so the directory itself is walked but not listed.
+	if rel == "." && s.args.PatternBase != patternBaseFull {
+		return decision{reason: "the search directory is not listed"}, nil
+	}
+
+	// shallow directories are still descended into, as their
+	// contents may be deep enough.
+	if depth := pathDepth(rel); depth < s.args.MinDepth {
+		return decision{reason: fmt.Sprintf("depth %d is below the minimum depth %d", depth, s.args.MinDepth)}, nil
+	}
+
+	r, ok := s.matchRule(s.target(path))
+	// the build context includes every file unless an include
+	// rule narrows it down.
+	if !ok && s.docker != nil && !hasInclude(s.rules) {
+		r, ok = rule{include: true}, true
+	}
+	if !ok {
+		return decision{reason: "no include pattern matched"}, nil
+	}
+	if !r.include {
+		s.logger.Debugf("path %s match exclude criteria %s", path, r.text)
+		return decision{reason: "exclude pattern " + r.text + " matched"}, nil
+	}
+
+	if s.args.BrokenSymlinks && !isBrokenLink(path, d) {
+		return decision{reason: "not a broken symbolic link"}, nil
+	}
+
+	// the size of the build context only accounts for the files
+	// it contains.
+	if s.docker != nil && isDir {
+		return decision{reason: "directories are not listed in a docker build context"}, nil
+	}
+
+	reason := "part of the docker build context"
+	if r.text != "" {
+		reason = fmt.Sprintf("include pattern %s matched", r.text)
+	}
+	return decision{include: true, pattern: r.text, reason: reason}, nil
+}
+
+// excludesTree reports whether a default exclude such as **/.git/**
+// matches everything below a directory. Such a pattern is tried on a
+// path below the directory, since it may match the contents of the
+// directory but not the directory itself.
+func (s *search) excludesTree(dir string) bool {
+	for _, p := range s.defaults {
+		if strings.HasSuffix(p.text, "/**") && p.Match(dir+"/x") {
+			return true
+		}
+	}
+	return false
+}
+
+// rootDevice returns the ID of the device holding the search
+// directory, or nil when it is not known.
+func rootDevice(logger *logrus.Entry, dir string) *uint64 {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		// the walk reports the missing search directory.
+		return nil
+	}
+	id, ok := deviceID(fi)
+	if !ok {
+		logger.Warnln("device IDs are not available, the search may cross file systems")
+		return nil
+	}
+	return &id
+}
+
+// mountPoint reports whether a directory is held by another device than
+// the search directory.
+func (s *search) mountPoint(d fs.DirEntry) bool {
+	if s.device == nil {
+		return false
+	}
+	fi, err := d.Info()
+	if err != nil {
+		return false
+	}
+	id, ok := deviceID(fi)
+	return ok && id != *s.device
+}
+
+// pathDepth returns the number of segments of a path relative to the
+// search directory, which is at depth 0.
+func pathDepth(rel string) int {
+	if rel == "." {
+		return 0
+	}
+	return strings.Count(rel, "/") + 1
+}
+
+// target returns the slash separated path the rules are matched against.
+func (s *search) target(path string) string {
+	if s.args.PatternBase == patternBaseFull {
+		return s.normalized(slashPath(path, filepath.Separator))
+	}
+	return s.normalized(relPath(s.args.TargetDir, path))
+}
+
+// normalized converts a string to the Unicode normalization form
+// configured for the search.
+func (s *search) normalized(text string) string {
+	if s.normalize == nil {
+		return text
+	}
+	return s.normalize(text)
+}
+
+// match returns the first pattern matching the path. When tracing,
+// every pattern is evaluated so the trace lists all of them.
+func (s *search) match(kind string, patterns []pattern, path string) (string, bool) {
+	if s.trace == nil {
+		return matchAny(patterns, path)
+	}
+
+	var first string
+	var found bool
+	for _, p := range patterns {
+		matched := p.Match(path)
+		s.trace.check(kind, p.text, matched)
+		if matched && !found {
+			first, found = p.text, true
+		}
+	}
+	return first, found
+}
+
+// matchRule returns the rule with the highest priority matching the
+// path. When tracing, every rule is evaluated so the trace lists all
+// of them.
+func (s *search) matchRule(path string) (rule, bool) {
+	var first rule
+	var found bool
+	for _, r := range s.rules {
+		if found && s.trace == nil {
+			break
+		}
+		matched := r.Match(path)
+		if s.trace != nil {
+			kind := "exclude"
+			if r.include {
+				kind = "include"
+			}
+			s.trace.check(kind, r.text, matched)
+		}
+		if matched && !found {
+			first, found = r, true
+		}
+	}
+	return first, found
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pathDepth(t *testing.T) {
+	assert.Equal(t, 0, pathDepth("."))
+	assert.Equal(t, 1, pathDepth("abc"))
+	assert.Equal(t, 3, pathDepth("abc/def/one.txt"))
+}
+
+func Test_Exec_MaxDepth(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	explainFile := filepath.Join(tempDir, "explain.txt")
+	args := Args{
+		Filter:      "abc/**",
+		MaxDepth:    2,
+		TargetDir:   tempDir,
+		Explain:     true,
+		ExplainFile: explainFile,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 6)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/test"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.yml"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/two.txt"))
+
+	content, err := os.ReadFile(explainFile)
+	fatalIf(err)
+	assert.Contains(t, string(content), "verdict: included, include pattern abc/** matched, maximum depth 2 reached, directory not descended into\n")
+	assert.NotContains(t, string(content), filepath.Join(tempDir, "abc/def/one.txt"))
+}
+
+func Test_Exec_MinDepth(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:    "**/one.*",
+		MinDepth:  3,
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/one.yml"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def/one.xml"))
+}
+
+func Test_Exec_MinAndMaxDepth(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	args := Args{
+		Filter:    "abc/**",
+		MinDepth:  2,
+		MaxDepth:  2,
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 5)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/test"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.txt"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/one.yml"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/two.txt"))
+}
+
+func Test_validateArg_DepthLimits(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	err := validateArgs(Args{Filter: "**/*.txt", MaxDepth: -1})
+	assert.EqualError(t, err, "depth limits must not be negative")
+
+	err = validateArgs(Args{Filter: "**/*.txt", MinDepth: 3, MaxDepth: 2})
+	assert.EqualError(t, err, "minimum depth 3 is greater than the maximum depth 2")
+
+	assert.NoError(t, validateArgs(Args{Filter: "**/*.txt", MinDepth: 3}))
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// walkFollow walks the tree rooted at root like filepath.WalkDir, but
+// descends into the directories symbolic links point to.
+func (w *walker) walkFollow(root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return w.visit(root, nil, err)
+	}
+	err = w.walkDir(root, fs.FileInfoToDirEntry(info), nil)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walkDir visits a path and, for a directory, its entries. The
+// ancestors are the directories walked to reach the path, used to
+// detect symbolic links leading back to one of them.
+func (w *walker) walkDir(path string, d fs.DirEntry, ancestors []os.FileInfo) error {
+	if err := w.visit(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if err = w.visit(path, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return w.visit(path, d, err)
+	}
+	ancestors = append(ancestors, info)
+
+	for _, entry := range entries {
+		child := filepath.Join(path, entry.Name())
+		if entry.Type()&fs.ModeSymlink != 0 {
+			entry = w.follow(child, entry, ancestors)
+		}
+		if err := w.walkDir(child, entry, ancestors); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// follow returns the entry of the directory a symbolic link points to.
+// Links to files, broken links and links leading back to one of the
+// ancestors, which would walk the same directories forever, are
+// returned unchanged.
+func (w *walker) follow(path string, link fs.DirEntry, ancestors []os.FileInfo) fs.DirEntry {
+	target, err := os.Stat(path)
+	if err != nil || !target.IsDir() {
+		return link
+	}
+	// os.SameFile compares the device and inode numbers on Unix
+	// systems.
+	for _, ancestor := range ancestors {
+		if os.SameFile(target, ancestor) {
+			w.logger.Debugf("path %s is a symbolic link loop, not followed", path)
+			return link
+		}
+	}
+	return fs.FileInfoToDirEntry(target)
+}
+
+// followFileInfo returns the details of the file a path resolves to,
+// including its real path. Broken links are described by their own
+// details.
+func followFileInfo(path string) (FileInfo, error) {
+	file, err := getFileInfo(path)
+	if err != nil || file.Broken {
+		return file, err
+	}
+	if file.IsSymlink {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return file, nil
+		}
+		file.IsDirectory = fi.IsDir()
+		file.Length = fi.Size()
+		file.LastModified = fi.ModTime().Format(time.RFC3339)
+	}
+
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return file, nil
+	}
+	file.RealPath = absPath(real)
+	return file, nil
+}
+
+// describeLink fills in the target of a symbolic link. The target of a
+// broken link is resolved against the directory of the link only.
+func describeLink(file *FileInfo, path string) {
+	file.IsSymlink = true
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		return
+	}
+	file.LinkTarget = target
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		file.Broken = true
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		file.ResolvedTarget = absPath(real)
+		return
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	file.ResolvedTarget = absPath(target)
+}
+
+// isBrokenLink reports whether a directory entry is a symbolic link
+// whose target does not exist.
+func isBrokenLink(path string, d fs.DirEntry) bool {
+	if d == nil || d.Type()&fs.ModeSymlink == 0 {
+		return false
+	}
+	_, err := os.Stat(path)
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// absPath returns the absolute form of a path, or the path itself when
+// the working directory is unknown.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Exec_FollowSymlinks(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.Symlink(filepath.Join(tempDir, "abc/def"), filepath.Join(tempDir, "linked")))
+
+	args := Args{
+		Filter:    "linked/*.txt",
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 0)
+
+	args.FollowSymlinks = true
+	files, err = applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	real, err := filepath.EvalSymlinks(filepath.Join(tempDir, "abc/def"))
+	fatalIf(err)
+	for _, file := range files {
+		assert.Equal(t, filepath.Join(tempDir, "linked", file.Name), file.Path)
+		assert.Equal(t, filepath.Join(real, file.Name), file.RealPath)
+	}
+}
+
+func Test_Exec_FollowSymlinks_Loop(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.Symlink(filepath.Join(tempDir, "abc"), filepath.Join(tempDir, "abc/def/loop")))
+
+	args := Args{
+		Filter:         "**/loop/**/*.txt",
+		TargetDir:      tempDir,
+		FollowSymlinks: true,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 0)
+
+	args.Filter = "**/loop"
+	files, err = applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, filepath.Join(tempDir, "abc/def/loop"), files[0].Path)
+
+	real, err := filepath.EvalSymlinks(filepath.Join(tempDir, "abc"))
+	fatalIf(err)
+	assert.Equal(t, real, files[0].RealPath)
+}
+
+func Test_Exec_FollowSymlinks_File(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc/data.txt"), []byte("content"), 0644))
+	fatalIf(os.Symlink("data.txt", filepath.Join(tempDir, "abc/link.txt")))
+	fatalIf(os.Symlink("missing.txt", filepath.Join(tempDir, "abc/broken.txt")))
+
+	args := Args{
+		Filter:         "abc/{link,broken}.txt",
+		TargetDir:      tempDir,
+		FollowSymlinks: true,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	for _, file := range files {
+		switch file.Name {
+		case "link.txt":
+			assert.Equal(t, int64(7), file.Length)
+			assert.Equal(t, "data.txt", filepath.Base(file.RealPath))
+		case "broken.txt":
+			assert.Empty(t, file.RealPath)
+		default:
+			t.Errorf("unexpected file %s", file.Path)
+		}
+	}
+}
+
+func Test_Exec_FollowSymlinks_Root(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	root := filepath.Join(tempDir, "root")
+	fatalIf(os.Symlink(filepath.Join(tempDir, "abc/def"), root))
+
+	args := Args{
+		Filter:         "*.txt",
+		TargetDir:      root,
+		FollowSymlinks: true,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func Test_getFileInfo_Symlink(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	real, err := filepath.EvalSymlinks(filepath.Join(tempDir, "abc/one.txt"))
+	fatalIf(err)
+	fatalIf(os.Symlink("one.txt", filepath.Join(tempDir, "abc/link.txt")))
+
+	file, err := getFileInfo(filepath.Join(tempDir, "abc/link.txt"))
+	assert.NoError(t, err)
+	assert.True(t, file.IsSymlink)
+	assert.False(t, file.Broken)
+	assert.Equal(t, "one.txt", file.LinkTarget)
+	assert.Equal(t, real, file.ResolvedTarget)
+
+	file, err = getFileInfo(filepath.Join(tempDir, "abc/one.txt"))
+	assert.NoError(t, err)
+	assert.False(t, file.IsSymlink)
+	assert.Empty(t, file.LinkTarget)
+	assert.Empty(t, file.ResolvedTarget)
+}
+
+func Test_getFileInfo_BrokenSymlink(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.Symlink("../missing.txt", filepath.Join(tempDir, "abc/broken.txt")))
+
+	file, err := getFileInfo(filepath.Join(tempDir, "abc/broken.txt"))
+	assert.NoError(t, err)
+	assert.True(t, file.IsSymlink)
+	assert.True(t, file.Broken)
+	assert.Equal(t, "../missing.txt", file.LinkTarget)
+	assert.Equal(t, filepath.Join(tempDir, "missing.txt"), file.ResolvedTarget)
+}
+
+func Test_Exec_BrokenSymlinks(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.Symlink("one.txt", filepath.Join(tempDir, "abc/link.txt")))
+	fatalIf(os.Symlink("missing.txt", filepath.Join(tempDir, "abc/def/broken.txt")))
+
+	args := Args{
+		Filter:         "**",
+		TargetDir:      tempDir,
+		BrokenSymlinks: true,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, filepath.Join(tempDir, "abc/def/broken.txt"), files[0].Path)
+	assert.True(t, files[0].Broken)
+
+	args.FollowSymlinks = true
+	files, err = applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, filepath.Join(tempDir, "abc/def/broken.txt"), files[0].Path)
+}
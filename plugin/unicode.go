@@ -0,0 +1,41 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unicode normalization forms the paths and patterns are converted to
+// before matching.
+const (
+	normalizationNone = "none"
+	normalizationNFC  = "nfc"
+	normalizationNFD  = "nfd"
+)
+
+// normalizer returns the function converting a string to the given
+// normalization form, or nil when strings are matched as they are.
+func normalizer(form string) func(string) string {
+	switch form {
+	case normalizationNFC:
+		return norm.NFC.String
+	case normalizationNFD:
+		return norm.NFD.String
+	}
+	return nil
+}
+
+// normalizeCompiler wraps a compiler so that patterns are converted
+// to the given normalization form before they are compiled.
+func normalizeCompiler(form string, compile Compiler) Compiler {
+	normalize := normalizer(form)
+	if normalize == nil {
+		return compile
+	}
+	return func(pattern string, opts MatchOptions) (Matcher, error) {
+		return compile(normalize(pattern), opts)
+	}
+}
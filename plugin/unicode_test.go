@@ -0,0 +1,113 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	// resumeNFC is résumé.pdf with precomposed accents, as typed.
+	resumeNFC = "r\u00e9sum\u00e9.pdf"
+	// resumeNFD is résumé.pdf with combining accents, as written
+	// by macOS.
+	resumeNFD = "re\u0301sume\u0301.pdf"
+)
+
+func Test_normalizer(t *testing.T) {
+	assert.Nil(t, normalizer(""))
+	assert.Nil(t, normalizer("none"))
+	assert.Equal(t, resumeNFC, normalizer("nfc")(resumeNFD))
+	assert.Equal(t, resumeNFD, normalizer("nfd")(resumeNFC))
+}
+
+func Test_normalizeCompiler(t *testing.T) {
+	tests := []struct {
+		form    string
+		pattern string
+		path    string
+		matched bool
+	}{
+		{form: "none", pattern: "**/" + resumeNFC, path: "docs/" + resumeNFD, matched: false},
+		{form: "nfc", pattern: "**/" + resumeNFC, path: "docs/" + resumeNFC, matched: true},
+		{form: "nfc", pattern: "**/" + resumeNFD, path: "docs/" + resumeNFC, matched: true},
+		{form: "nfd", pattern: "**/" + resumeNFC, path: "docs/" + resumeNFD, matched: true},
+		// a character class matches a single rune, while NFD
+		// decomposes accented letters into two.
+		{form: "nfd", pattern: "**/r[\u00e9]sum*", path: "docs/" + resumeNFD, matched: false},
+	}
+	for _, test := range tests {
+		m, err := normalizeCompiler(test.form, compileAnt)(test.pattern, MatchOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, test.matched, m.Match(test.path), "%s %q against %q", test.form, test.pattern, test.path)
+	}
+}
+
+func Test_Exec_UnicodeNormalization(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc", resumeNFD), []byte{}, 0644))
+
+	args := Args{
+		Filter:    "abc/" + resumeNFC,
+		TargetDir: tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	args.UnicodeNormalization = "nfc"
+	files, err = applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	assert.Equal(t, resumeNFC, files[0].Name)
+	assert.Equal(t, filepath.Join(tempDir, "abc", resumeNFC), files[0].Path)
+	assert.Equal(t, filepath.Join(tempDir, "abc", resumeNFD), files[0].RawPath)
+
+	_, err = os.Stat(files[0].RawPath)
+	assert.NoError(t, err)
+}
+
+func Test_Exec_UnicodeNormalization_Excludes(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "abc", resumeNFD), []byte{}, 0644))
+
+	args := Args{
+		Filter:               "abc/*",
+		Excludes:             "**/" + resumeNFC + ",**/*.{txt,yml}",
+		UnicodeNormalization: "nfc",
+		TargetDir:            tempDir,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.RawPath)
+	}
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/def"))
+	assert.Contains(t, paths, filepath.Join(tempDir, "abc/test"))
+}
+
+func Test_validateArg_UnknownUnicodeNormalization(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	err := validateArgs(Args{
+		Filter:               "**/*.txt",
+		UnicodeNormalization: "nfkc",
+	})
+	assert.EqualError(t, err, `unknown unicode normalization "nfkc", expected one of none, nfc or nfd`)
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// expandArgs expands the pipeline variable references of the glob,
+// excludes and dir settings.
+func expandArgs(args Args) (Args, error) {
+	vars := pipelineVariables(args.Pipeline)
+	quote := patternQuoter(args)
+
+	var err error
+	if args.Filter, err = expandVariables(args.Filter, vars, quote); err != nil {
+		return args, fmt.Errorf("glob: %w", err)
+	}
+	if args.Excludes, err = expandVariables(args.Excludes, vars, quote); err != nil {
+		return args, fmt.Errorf("excludes: %w", err)
+	}
+	if args.TargetDir, err = expandVariables(args.TargetDir, vars, separatorFree("directories")); err != nil {
+		return args, fmt.Errorf("dir: %w", err)
+	}
+	return args, nil
+}
+
+// pipelineVariables returns the values of the pipeline metadata keyed
+// by the name of their environment variable. Lists such as the failed
+// steps are left out, as no separator keeps their items apart in a
+// pattern.
+func pipelineVariables(p Pipeline) map[string]string {
+	vars := map[string]string{}
+	collectVariables(reflect.ValueOf(p), vars)
+	return vars
+}
+
+func collectVariables(v reflect.Value, vars map[string]string) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			collectVariables(field, vars)
+			continue
+		}
+		name := v.Type().Field(i).Tag.Get("envconfig")
+		if name == "" {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.String:
+			vars[name] = field.String()
+		case reflect.Int, reflect.Int64:
+			vars[name] = strconv.FormatInt(field.Int(), 10)
+		case reflect.Bool:
+			vars[name] = strconv.FormatBool(field.Bool())
+		}
+	}
+}
+
+// patternMetacharacters are the characters of Ant, glob and gitignore
+// patterns escaped in variable values, along with the comma separating
+// the patterns.
+const patternMetacharacters = `\*?[]{}()!|,`
+
+// patternQuoter returns the function inserting a variable value into
+// the patterns of the match mode, so that the value matches literally.
+// The syntax of custom matchers is not known, so their values may not
+// hold the comma separating patterns and are inserted as they are.
+func patternQuoter(args Args) func(name, value string) (string, error) {
+	if args.Compiler != nil {
+		return separatorFree("patterns")
+	}
+	switch args.MatchMode {
+	case "", "ant", "glob", "gitignore":
+		return func(name, value string) (string, error) {
+			var b strings.Builder
+			for _, r := range value {
+				if strings.ContainsRune(patternMetacharacters, r) {
+					b.WriteByte('\\')
+				}
+				b.WriteRune(r)
+			}
+			return b.String(), nil
+		}
+	case "regex":
+		return func(name, value string) (string, error) {
+			return strings.ReplaceAll(regexp.QuoteMeta(value), ",", `\,`), nil
+		}
+	default:
+		return separatorFree("patterns")
+	}
+}
+
+// separatorFree returns the function inserting a variable value as it
+// is into a comma separated list of the given items, rejecting values
+// that hold a comma.
+func separatorFree(items string) func(name, value string) (string, error) {
+	return func(name, value string) (string, error) {
+		if strings.Contains(value, ",") {
+			return "", fmt.Errorf("variable %q holds a comma, which separates %s", name, items)
+		}
+		return value, nil
+	}
+}
+
+// expandVariables replaces the ${NAME} references of s with the value
+// of the variable, as returned by quote. Only the braced form is
+// expanded, so that the $ of a regular expression is left alone, and
+// $${ escapes a reference. A reference to an unknown or empty variable
+// is an error, since dropping the value would widen the pattern, as is
+// a value holding a line break, which separates patterns.
+func expandVariables(s string, vars map[string]string, quote func(name, value string) (string, error)) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", errors.New("unterminated variable reference")
+		}
+		name := s[i+2 : i+end]
+		value, ok := vars[name]
+		switch {
+		case !ok:
+			return "", fmt.Errorf("unknown variable %q", name)
+		case value == "":
+			return "", fmt.Errorf("variable %q is empty", name)
+		case strings.ContainsAny(value, "\r\n"):
+			return "", fmt.Errorf("variable %q holds a line break", name)
+		}
+		quoted, err := quote(name, value)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(quoted)
+		s = s[i+end+1:]
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pipelineVariables(t *testing.T) {
+	var p Pipeline
+	p.Semver.Version = "1.2.3"
+	p.Semver.Short = "1.2"
+	p.Build.Number = 42
+	p.Repo.Private = true
+	p.Commit.Author.Username = "octocat"
+	p.Stage.DependsOn = []string{"build", "test"}
+
+	vars := pipelineVariables(p)
+	assert.Equal(t, "1.2.3", vars["DRONE_SEMVER"])
+	assert.Equal(t, "1.2", vars["DRONE_SEMVER_SHORT"])
+	assert.Equal(t, "42", vars["DRONE_BUILD_NUMBER"])
+	assert.Equal(t, "true", vars["DRONE_REPO_PRIVATE"])
+	assert.Equal(t, "octocat", vars["DRONE_COMMIT_AUTHOR"])
+	assert.NotContains(t, vars, "DRONE_STAGE_DEPENDS_ON")
+
+	value, ok := vars["DRONE_TAG"]
+	assert.True(t, ok)
+	assert.Empty(t, value)
+}
+
+func Test_expandVariables(t *testing.T) {
+	vars := map[string]string{
+		"DRONE_SEMVER":       "1.2.3",
+		"DRONE_BUILD_NUMBER": "42",
+	}
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{text: "dist/**/app-${DRONE_SEMVER}*.tar.gz", expected: "dist/**/app-1.2.3*.tar.gz"},
+		{text: "${DRONE_BUILD_NUMBER}/${DRONE_SEMVER}", expected: "42/1.2.3"},
+		{text: `\.tar\.gz$`, expected: `\.tar\.gz$`},
+		{text: "$DRONE_SEMVER", expected: "$DRONE_SEMVER"},
+		{text: "$${DRONE_SEMVER}", expected: "${DRONE_SEMVER}"},
+		{text: "**/*.{yml,yaml}", expected: "**/*.{yml,yaml}"},
+	}
+	for _, test := range tests {
+		expanded, err := expandVariables(test.text, vars, patternQuoter(Args{}))
+		assert.NoError(t, err, test.text)
+		assert.Equal(t, test.expected, expanded, test.text)
+	}
+}
+
+func Test_expandVariables_Invalid(t *testing.T) {
+	vars := map[string]string{
+		"DRONE_SEMVER":         "1.2.3",
+		"DRONE_TAG":            "",
+		"DRONE_COMMIT_MESSAGE": "fix\nbuild",
+	}
+	quote := patternQuoter(Args{})
+
+	_, err := expandVariables("app-${HOME}.tar.gz", vars, quote)
+	assert.EqualError(t, err, `unknown variable "HOME"`)
+
+	_, err = expandVariables("app-${DRONE_SEMVER.tar.gz", vars, quote)
+	assert.EqualError(t, err, "unterminated variable reference")
+
+	_, err = expandVariables("dist/**/app-${DRONE_TAG}*.tar.gz", vars, quote)
+	assert.EqualError(t, err, `variable "DRONE_TAG" is empty`)
+
+	_, err = expandVariables("${DRONE_COMMIT_MESSAGE}", vars, quote)
+	assert.EqualError(t, err, `variable "DRONE_COMMIT_MESSAGE" holds a line break`)
+}
+
+func Test_patternQuoter(t *testing.T) {
+	value := "feature/[x]*{a,b}"
+	tests := []struct {
+		args     Args
+		expected string
+	}{
+		{args: Args{}, expected: `feature/\[x\]\*\{a\,b\}`},
+		{args: Args{MatchMode: "glob"}, expected: `feature/\[x\]\*\{a\,b\}`},
+		{args: Args{MatchMode: "regex"}, expected: `feature/\[x\]\*\{a\,b\}`},
+	}
+	for _, test := range tests {
+		quoted, err := patternQuoter(test.args)("DRONE_BRANCH", value)
+		assert.NoError(t, err, test.args.MatchMode)
+		assert.Equal(t, test.expected, quoted, test.args.MatchMode)
+
+		patterns, err := compileFor(test.args, splitPatterns(quoted))
+		assert.NoError(t, err, test.args.MatchMode)
+		assert.Len(t, patterns, 1, test.args.MatchMode)
+		assert.True(t, patterns[0].Match(value), test.args.MatchMode)
+		assert.False(t, patterns[0].Match("feature/x-a"), test.args.MatchMode)
+	}
+
+	_, err := patternQuoter(Args{Compiler: compileGlob})("DRONE_BRANCH", "a,b")
+	assert.EqualError(t, err, `variable "DRONE_BRANCH" holds a comma, which separates patterns`)
+}
+
+func Test_expandArgs(t *testing.T) {
+	args := Args{
+		Filter:    "dist/**/app-${DRONE_SEMVER}*.tar.gz",
+		Excludes:  "**/*-${DRONE_BUILD_NUMBER}.tar.gz",
+		TargetDir: "out/${DRONE_STAGE_NAME}",
+	}
+	args.Semver.Version = "1.2.3"
+	args.Build.Number = 42
+	args.Stage.Name = "linux"
+
+	args, err := expandArgs(args)
+	assert.NoError(t, err)
+	assert.Equal(t, "dist/**/app-1.2.3*.tar.gz", args.Filter)
+	assert.Equal(t, "**/*-42.tar.gz", args.Excludes)
+	assert.Equal(t, "out/linux", args.TargetDir)
+
+	_, err = expandArgs(Args{Filter: "**/*.go", Excludes: "${DRONE_UNKNOWN}"})
+	assert.EqualError(t, err, `excludes: unknown variable "DRONE_UNKNOWN"`)
+
+	args = Args{Filter: "**/*.go", TargetDir: "out/${DRONE_COMMIT_BRANCH}"}
+	args.Commit.Branch = "a,b"
+	_, err = expandArgs(args)
+	assert.EqualError(t, err, `dir: variable "DRONE_COMMIT_BRANCH" holds a comma, which separates directories`)
+}
+
+func Test_Exec_PipelineVariables(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	fatalIf(os.MkdirAll(filepath.Join(tempDir, "dist/linux"), 0755))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "dist/linux/app-1.2.3.tar.gz"), []byte{}, 0644))
+	fatalIf(os.WriteFile(filepath.Join(tempDir, "dist/linux/app-1.2.2.tar.gz"), []byte{}, 0644))
+
+	output := filepath.Join(tempDir, "drone_output.properties")
+	os.Setenv("DRONE_OUTPUT", output)
+	defer os.Unsetenv("DRONE_OUTPUT")
+
+	args := Args{
+		Filter:    "**/app-${DRONE_SEMVER}*.tar.gz",
+		TargetDir: filepath.Join(tempDir, "dist"),
+	}
+	args.Semver.Version = "1.2.3"
+
+	err := Exec(context.Background(), args)
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(output)
+	fatalIf(err)
+	assert.Contains(t, string(content), "app-1.2.3.tar.gz")
+	assert.NotContains(t, string(content), "app-1.2.2.tar.gz")
+}
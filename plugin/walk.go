@@ -0,0 +1,204 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Error modes select how paths that cannot be read are handled.
+const (
+	onErrorFail   = "fail"
+	onErrorSkip   = "skip"
+	onErrorReport = "report"
+)
+
+// WalkError describes a path that could not be read during the search.
+type WalkError struct {
+	Path    string `json:"path"`
+	Class   string `json:"class"`
+	Message string `json:"error"`
+}
+
+// walker collects the files of a search while walking the tree.
+type walker struct {
+	args      Args
+	logger    *logrus.Entry
+	search    *search
+	normalize func(string) string
+
+	// root is the search directory being walked, and seen holds the
+	// absolute paths of the files found under the previous ones.
+	root string
+	seen map[string]bool
+
+	files  []FileInfo
+	errors []WalkError
+}
+
+// findFiles walks the search directories and returns the matching
+// files, along with the paths that could not be read when errors are
+// reported.
+func findFiles(logger *logrus.Entry, args Args) ([]FileInfo, []WalkError, error) {
+	s, err := newSearch(logger, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := &walker{
+		args:      args,
+		logger:    logger,
+		search:    s,
+		normalize: normalizer(args.UnicodeNormalization),
+		seen:      map[string]bool{},
+		errors:    []WalkError{},
+	}
+	for _, root := range searchRoots(args.TargetDir) {
+		if err = s.setRoot(root); err != nil {
+			break
+		}
+		w.args.TargetDir = root
+		w.root = root
+		if args.FollowSymlinks {
+			err = w.walkFollow(root)
+		} else {
+			err = filepath.WalkDir(root, w.visit)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if cerr := s.close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return []FileInfo{}, nil, err
+	}
+	return w.files, w.errors, nil
+}
+
+// searchRoots returns the search directories of the dir setting,
+// separated by commas or newlines. The current directory is searched
+// when none is given.
+func searchRoots(dir string) []string {
+	var roots []string
+	seen := map[string]bool{}
+	for _, root := range strings.FieldsFunc(dir, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	}) {
+		root = strings.TrimSpace(root)
+		if root == "" || seen[root] {
+			continue
+		}
+		seen[root] = true
+		roots = append(roots, root)
+	}
+	if len(roots) == 0 {
+		return []string{"."}
+	}
+	return roots
+}
+
+// visit is the filepath.WalkDirFunc of the search.
+func (w *walker) visit(path string, d fs.DirEntry, e error) error {
+	if e != nil {
+		// without the search directory there is nothing to search.
+		if path == w.args.TargetDir && d == nil {
+			return e
+		}
+		// a directory that cannot be read was already visited before
+		// its entries were listed.
+		return w.fail(path, e)
+	}
+
+	dec, err := w.search.decide(path, d)
+	if err != nil {
+		return err
+	}
+	if dec.include {
+		stat := getFileInfo
+		if w.args.FollowSymlinks {
+			stat = followFileInfo
+		}
+		file, err := stat(path)
+		if err != nil {
+			if err := w.fail(path, err); err != nil {
+				return logError(w.logger, fmt.Sprintf("error to get file info of path %s", path), err)
+			}
+			return nil
+		}
+
+		// overlapping search directories find the same files.
+		key := absPath(path)
+		if w.seen[key] {
+			w.logger.Debugf("path %s was already found under another search directory", path)
+			return skip(dec)
+		}
+		w.seen[key] = true
+
+		file.Root = outputPath(w.root, w.args.PathStyle)
+		file.Path = outputPath(path, w.args.PathStyle)
+		if file.RealPath != "" {
+			file.RealPath = outputPath(file.RealPath, w.args.PathStyle)
+		}
+		if file.ResolvedTarget != "" {
+			file.ResolvedTarget = outputPath(file.ResolvedTarget, w.args.PathStyle)
+		}
+		file.Pattern = dec.pattern
+		if w.normalize != nil {
+			file.RawPath = file.Path
+			file.Name = w.normalize(file.Name)
+			file.Path = w.normalize(file.Path)
+			file.Root = w.normalize(file.Root)
+		}
+		w.files = append(w.files, file)
+	}
+	return skip(dec)
+}
+
+// skip returns filepath.SkipDir for a directory that is not descended
+// into.
+func skip(dec decision) error {
+	if dec.skipDir {
+		return filepath.SkipDir
+	}
+	return nil
+}
+
+// fail handles a path that cannot be read. The error is returned to
+// abort the search in fail mode, and recorded in report mode.
+func (w *walker) fail(path string, err error) error {
+	switch w.args.OnError {
+	case onErrorSkip:
+		w.logger.Debugf("skipping path %s: %v", path, err)
+		return nil
+	case onErrorReport:
+		w.logger.Debugf("reporting path %s: %v", path, err)
+		w.errors = append(w.errors, WalkError{
+			Path:    outputPath(path, w.args.PathStyle),
+			Class:   errorClass(err),
+			Message: err.Error(),
+		})
+		return nil
+	}
+	return err
+}
+
+// errorClass returns a stable identifier of the kind of error.
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return "permission_denied"
+	case errors.Is(err, fs.ErrNotExist):
+		return "not_found"
+	}
+	return "io_error"
+}
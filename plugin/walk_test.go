@@ -0,0 +1,258 @@
+// Copyright 2020 the Drone Authors. All rights reserved.
+// Use of this source code is governed by the Blue Oak Model License
+// that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_errorClass(t *testing.T) {
+	assert.Equal(t, "permission_denied", errorClass(&fs.PathError{Op: "open", Path: "abc", Err: fs.ErrPermission}))
+	assert.Equal(t, "not_found", errorClass(fmt.Errorf("lstat: %w", fs.ErrNotExist)))
+	assert.Equal(t, "io_error", errorClass(errors.New("input/output error")))
+}
+
+// deletedEntry returns the directory entry of a file that is removed
+// once listed, as happens when a file is deleted during the search.
+func deletedEntry(dir, name string) fs.DirEntry {
+	fatalIf(os.WriteFile(filepath.Join(dir, name), []byte{}, 0644))
+	entries, err := os.ReadDir(dir)
+	fatalIf(err)
+	fatalIf(os.Remove(filepath.Join(dir, name)))
+	for _, entry := range entries {
+		if entry.Name() == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+func newWalker(args Args) *walker {
+	s, err := newSearch(NoopLogger(), args)
+	fatalIf(err)
+	root := args.TargetDir
+	if root == "" {
+		root = "."
+	}
+	return &walker{args: args, logger: NoopLogger(), search: s, root: root, seen: map[string]bool{}, errors: []WalkError{}}
+}
+
+func Test_walker_Visit_DeletedFile(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	entry := deletedEntry(tempDir, "gone.txt")
+	path := filepath.Join(tempDir, "gone.txt")
+
+	args := Args{Filter: "*.txt", TargetDir: tempDir}
+
+	args.OnError = "fail"
+	err := newWalker(args).visit(path, entry, nil)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	args.OnError = "skip"
+	w := newWalker(args)
+	assert.NoError(t, w.visit(path, entry, nil))
+	assert.Empty(t, w.files)
+	assert.Empty(t, w.errors)
+
+	args.OnError = "report"
+	w = newWalker(args)
+	assert.NoError(t, w.visit(path, entry, nil))
+	assert.Empty(t, w.files)
+	assert.Len(t, w.errors, 1)
+	assert.Equal(t, path, w.errors[0].Path)
+	assert.Equal(t, "not_found", w.errors[0].Class)
+}
+
+func Test_walker_Visit_UnreadableDirectory(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	entries, err := os.ReadDir(tempDir)
+	fatalIf(err)
+	var entry fs.DirEntry
+	for _, e := range entries {
+		if e.Name() == "abc" {
+			entry = e
+		}
+	}
+	path := filepath.Join(tempDir, "abc")
+	readErr := &fs.PathError{Op: "open", Path: path, Err: fs.ErrPermission}
+
+	args := Args{Filter: "**", TargetDir: tempDir}
+
+	args.OnError = "fail"
+	assert.Equal(t, readErr, newWalker(args).visit(path, entry, readErr))
+
+	args.OnError = "report"
+	w := newWalker(args)
+	assert.NoError(t, w.visit(path, entry, readErr))
+	// the directory was listed when it was first visited.
+	assert.Empty(t, w.files)
+	assert.Equal(t, []WalkError{{
+		Path:    path,
+		Class:   "permission_denied",
+		Message: "open " + path + ": permission denied",
+	}}, w.errors)
+}
+
+func Test_findFiles_MissingDirectory(t *testing.T) {
+	_, _, err := findFiles(NoopLogger(), Args{
+		Filter:    "**/*.txt",
+		OnError:   "skip",
+		TargetDir: "/missing/findfiles",
+	})
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func Test_Exec_ReportPermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	locked := filepath.Join(tempDir, "abc/def")
+	fatalIf(os.Chmod(locked, 0))
+	defer os.Chmod(locked, 0755)
+
+	output := filepath.Join(tempDir, "drone_output.properties")
+	os.Setenv("DRONE_OUTPUT", output)
+	defer os.Unsetenv("DRONE_OUTPUT")
+
+	err := Exec(context.Background(), Args{
+		Filter:    "**/*.txt",
+		OnError:   "report",
+		TargetDir: tempDir,
+	})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(output)
+	fatalIf(err)
+	assert.Contains(t, string(content), `FILES_ERRORS=[{"path":"`+locked+`","class":"permission_denied"`)
+}
+
+func Test_Exec_ReportNoErrors(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	output := filepath.Join(tempDir, "drone_output.properties")
+	os.Setenv("DRONE_OUTPUT", output)
+	defer os.Unsetenv("DRONE_OUTPUT")
+
+	err := Exec(context.Background(), Args{
+		Filter:    "**/*.txt",
+		OnError:   "report",
+		TargetDir: tempDir,
+	})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(output)
+	fatalIf(err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "FILES_INFO="))
+	assert.Equal(t, "FILES_ERRORS=[]", lines[1])
+}
+
+func Test_validateArg_UnknownErrorMode(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	err := validateArgs(Args{
+		Filter:  "**/*.txt",
+		OnError: "ignore",
+	})
+	assert.EqualError(t, err, `unknown error mode "ignore", expected one of fail, skip or report`)
+}
+
+func Test_searchRoots(t *testing.T) {
+	assert.Equal(t, []string{"."}, searchRoots(""))
+	assert.Equal(t, []string{"build"}, searchRoots("build"))
+	assert.Equal(t, []string{"build", "dist", "target"}, searchRoots("build, dist\ntarget,,build\n"))
+}
+
+func Test_Exec_MultipleRoots(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	first := filepath.Join(tempDir, "abc")
+	second := filepath.Join(tempDir, "abc/test")
+	args := Args{
+		Filter:    "*.txt,**/*.go",
+		TargetDir: first + "\n" + second,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+
+	roots := map[string]string{}
+	for _, file := range files {
+		roots[file.Path] = file.Root
+	}
+	assert.Equal(t, map[string]string{
+		filepath.Join(tempDir, "abc/one.txt"):                        first,
+		filepath.Join(tempDir, "abc/two.txt"):                        first,
+		filepath.Join(tempDir, "abc/test/harness/community/main.go"): first,
+	}, roots)
+}
+
+func Test_Exec_OverlappingRoots(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	first := filepath.Join(tempDir, "abc/def")
+	second := filepath.Join(tempDir, "abc")
+	args := Args{
+		Filter:    "**/*.txt",
+		TargetDir: first + "," + second,
+	}
+
+	files, err := applyFilter(NoopLogger(), args)
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	roots := map[string]string{}
+	for _, file := range files {
+		roots[file.Path] = file.Root
+	}
+	assert.Equal(t, map[string]string{
+		filepath.Join(tempDir, "abc/def/one.txt"): first,
+		filepath.Join(tempDir, "abc/def/two.txt"): first,
+		filepath.Join(tempDir, "abc/one.txt"):     second,
+		filepath.Join(tempDir, "abc/two.txt"):     second,
+	}, roots)
+}
+
+func Test_findFiles_MissingRoot(t *testing.T) {
+	tempDir := setupFilesAndFolders()
+	defer os.RemoveAll(tempDir)
+
+	_, _, err := findFiles(NoopLogger(), Args{
+		Filter:    "**/*.txt",
+		TargetDir: tempDir + ",/missing/findfiles",
+	})
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func Test_validateArg_DockerContextRoots(t *testing.T) {
+	os.Setenv("DRONE_OUTPUT", "/tmp")
+
+	err := validateArgs(Args{
+		DockerContext: true,
+		TargetDir:     "build,dist",
+	})
+	assert.EqualError(t, err, "a docker build context has a single directory, got 2")
+}